@@ -9,8 +9,10 @@ import (
 	"syscall"
 
 	"github.com/projecteru2/agent/api"
+	"github.com/projecteru2/agent/logs"
 	"github.com/projecteru2/agent/manager/node"
 	"github.com/projecteru2/agent/manager/workload"
+	"github.com/projecteru2/agent/rpc"
 	"github.com/projecteru2/agent/types"
 	"github.com/projecteru2/agent/utils"
 	"github.com/projecteru2/agent/version"
@@ -50,7 +52,7 @@ func serve(c *cli.Context) error {
 	defer cancel()
 
 	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGUSR1)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGUSR1, syscall.SIGHUP)
 	errChan := make(chan error, 2)
 	defer close(errChan)
 
@@ -83,24 +85,54 @@ func serve(c *cli.Context) error {
 		}
 	})
 
-	apiHandler := api.NewHandler(config, workloadsManager)
-	_ = utils.Pool.Submit(apiHandler.Serve)
+	apiTLS, err := api.NewTLS(config.API.TLS)
+	if err != nil {
+		return err
+	}
+	// wait for the api servers shutting down
+	wg.Add(2)
+	apiHandler := api.NewHandler(config, workloadsManager, apiTLS)
+	_ = utils.Pool.Submit(func() {
+		defer wg.Done()
+		apiHandler.Serve(ctx)
+	})
+
+	agentRPC := rpc.New(config, workloadsManager, apiTLS)
+	_ = utils.Pool.Submit(func() {
+		defer wg.Done()
+		agentRPC.Serve(ctx)
+	})
 
 	_ = utils.Pool.Submit(func() {
-		select {
-		case <-ctx.Done():
-			logger.Info(c.Context, "[agent] Agent exiting")
-		case err := <-errChan:
-			logger.Error(c.Context, err, "[agent] Got error, exiting")
-			cancel()
-		case sig := <-signalChan:
-			logger.Infof(c.Context, "[agent] Agent caught system signal %v", sig)
-			if sig != syscall.SIGUSR1 {
-				if err := nodeManager.Exit(); err != nil {
-					logger.Error(c.Context, err, "[agent] node manager exits with err")
+		for {
+			select {
+			case <-ctx.Done():
+				logger.Info(c.Context, "[agent] Agent exiting")
+				return
+			case err := <-errChan:
+				logger.Error(c.Context, err, "[agent] Got error, exiting")
+				cancel()
+				return
+			case sig := <-signalChan:
+				logger.Infof(c.Context, "[agent] Agent caught system signal %v", sig)
+				if sig == syscall.SIGHUP {
+					// reload certificates of log forwards and api
+					logs.ReloadTLS()
+					if apiTLS != nil {
+						if err := apiTLS.Reload(); err != nil {
+							logger.Error(c.Context, err, "[agent] failed to reload api certificate, keep the old one")
+						}
+					}
+					continue
 				}
+				if sig != syscall.SIGUSR1 {
+					if err := nodeManager.Exit(); err != nil {
+						logger.Error(c.Context, err, "[agent] node manager exits with err")
+					}
+				}
+				cancel()
+				return
 			}
-			cancel()
 		}
 	})
 
@@ -181,9 +213,21 @@ func main() {
 			&cli.StringFlag{
 				Name:    "api-addr",
 				Value:   "",
-				Usage:   "agent api serving address",
+				Usage:   "agent api serving address, can be unix:///path/to/socket",
 				EnvVars: []string{"ERU_AGENT_API_ADDR"},
 			},
+			&cli.StringSliceFlag{
+				Name:    "api-addrs",
+				Value:   &cli.StringSlice{},
+				Usage:   "more agent api serving addresses, can be unix:///path/to/socket",
+				EnvVars: []string{"ERU_AGENT_API_ADDRS"},
+			},
+			&cli.StringFlag{
+				Name:    "api-grpc-addr",
+				Value:   "",
+				Usage:   "agent grpc api serving address",
+				EnvVars: []string{"ERU_AGENT_API_GRPC_ADDR"},
+			},
 			&cli.StringSliceFlag{
 				Name:    "log-forwards",
 				Value:   &cli.StringSlice{},
@@ -196,6 +240,12 @@ func main() {
 				Usage:   "forward stdout out? yes/no",
 				EnvVars: []string{"ERU_AGENT_LOG_STDOUT"},
 			},
+			&cli.StringFlag{
+				Name:    "log-spool-dir",
+				Value:   "",
+				Usage:   "directory to spool logs while forwards are down",
+				EnvVars: []string{"ERU_AGENT_LOG_SPOOL_DIR"},
+			},
 			&cli.StringFlag{
 				Name:    "pidfile",
 				Value:   "",
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+
+	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/types"
+)
+
+// Authenticator checks credentials of api requests against the scopes of routes.
+// If no credentials are configured, all scopes except admin are allowed.
+type Authenticator struct {
+	credentials []types.APICredential
+	anonymous   map[string]bool
+}
+
+// NewAuthenticator .
+func NewAuthenticator(config *types.APIConfig) *Authenticator {
+	a := &Authenticator{
+		credentials: config.Auth.Credentials,
+		anonymous:   map[string]bool{},
+	}
+	if config.Token != "" {
+		a.credentials = append([]types.APICredential{{Token: config.Token, Scopes: []string{common.ScopeAll}}}, a.credentials...)
+	}
+	for _, scope := range config.Auth.Anonymous {
+		// admin always requires credentials, such config is rejected when loading
+		if scope != common.ScopeAdmin && scope != common.ScopeAll {
+			a.anonymous[scope] = true
+		}
+	}
+	if len(a.credentials) == 0 {
+		for _, scope := range []string{common.ScopeLogs, common.ScopeMetrics, common.ScopeDebug, common.ScopeWorkloads} {
+			a.anonymous[scope] = true
+		}
+	}
+	return a
+}
+
+// Authorize checks the authorization header, which is a bearer token or basic auth,
+// returns ErrUnauthenticated if the credentials are missing or invalid, ErrPermissionDenied if the scope is not granted
+func (a *Authenticator) Authorize(authorization string, scope string) error {
+	if a.anonymous[scope] {
+		return nil
+	}
+	// no one can be granted
+	if len(a.credentials) == 0 {
+		return common.ErrPermissionDenied
+	}
+	credential := a.find(authorization)
+	if credential == nil {
+		return common.ErrUnauthenticated
+	}
+	for _, s := range credential.Scopes {
+		if s == scope || s == common.ScopeAll {
+			return nil
+		}
+	}
+	return common.ErrPermissionDenied
+}
+
+func (a *Authenticator) find(authorization string) *types.APICredential {
+	kind, value, ok := strings.Cut(authorization, " ")
+	if !ok {
+		return nil
+	}
+	switch strings.ToLower(kind) {
+	case "bearer":
+		for i, credential := range a.credentials {
+			if credential.Token != "" && equal(credential.Token, value) {
+				return &a.credentials[i]
+			}
+		}
+	case "basic":
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil
+		}
+		username, password, ok := strings.Cut(string(decoded), ":")
+		if !ok {
+			return nil
+		}
+		for i, credential := range a.credentials {
+			if credential.Username != "" && equal(credential.Username, username) && equal(credential.Password, password) {
+				return &a.credentials[i]
+			}
+		}
+	}
+	return nil
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticator(t *testing.T) {
+	basic := func(username, password string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+	}
+
+	// without credentials, only admin is denied
+	a := NewAuthenticator(&types.APIConfig{})
+	assert.NoError(t, a.Authorize("", common.ScopeLogs))
+	assert.NoError(t, a.Authorize("", common.ScopeDebug))
+	assert.Equal(t, common.ErrPermissionDenied, a.Authorize("Bearer gendo", common.ScopeAdmin))
+
+	// admin can't be allowed without credentials
+	a = NewAuthenticator(&types.APIConfig{Token: "gendo", Auth: types.APIAuthConfig{Anonymous: []string{common.ScopeAdmin, common.ScopeAll}}})
+	assert.Equal(t, common.ErrUnauthenticated, a.Authorize("", common.ScopeAdmin))
+	assert.Equal(t, common.ErrUnauthenticated, a.Authorize("", common.ScopeLogs))
+
+	a = NewAuthenticator(&types.APIConfig{
+		Token: "gendo",
+		Auth: types.APIAuthConfig{
+			Credentials: []types.APICredential{
+				{Token: "rei", Scopes: []string{common.ScopeLogs}},
+				{Username: "misato", Password: "katsuragi", Scopes: []string{common.ScopeDebug, common.ScopeMetrics}},
+			},
+			Anonymous: []string{common.ScopeMetrics},
+		},
+	})
+	for _, c := range []struct {
+		authorization string
+		scope         string
+		err           error
+	}{
+		{"", common.ScopeMetrics, nil},
+		{"", common.ScopeLogs, common.ErrUnauthenticated},
+		{"Bearer rei", common.ScopeLogs, nil},
+		{"bearer rei", common.ScopeLogs, nil},
+		{"Bearer rei", common.ScopeDebug, common.ErrPermissionDenied},
+		{"Bearer shinji", common.ScopeLogs, common.ErrUnauthenticated},
+		{basic("misato", "katsuragi"), common.ScopeDebug, nil},
+		{basic("misato", "ritsuko"), common.ScopeDebug, common.ErrUnauthenticated},
+		{basic("misato", "katsuragi"), common.ScopeAdmin, common.ErrPermissionDenied},
+		{"Basic !!!", common.ScopeDebug, common.ErrUnauthenticated},
+		// api.token is granted all scopes
+		{"Bearer gendo", common.ScopeAdmin, nil},
+		{"Bearer gendo", common.ScopeLogs, nil},
+	} {
+		assert.Equal(t, c.err, a.Authorize(c.authorization, c.scope), "%s %s", c.authorization, c.scope)
+	}
+}
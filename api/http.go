@@ -1,20 +1,30 @@
 package api
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"net"
 	"net/http"
+	"regexp"
 	"runtime/pprof" //nolint:nolintlint
+	"strconv"
+	"strings"
 	"time"
 
 	// enable profile
 	_ "net/http/pprof" //nolint
 
+	"github.com/projecteru2/agent/common"
 	"github.com/projecteru2/agent/manager/workload"
 	"github.com/projecteru2/agent/types"
+	"github.com/projecteru2/agent/utils"
 	"github.com/projecteru2/agent/version"
 	"github.com/projecteru2/core/log"
 
 	"github.com/bmizerany/pat"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -25,6 +35,9 @@ type JSON map[string]interface{}
 type Handler struct {
 	config           *types.Config
 	workloadsManager *workload.Manager
+	authenticator    *Authenticator
+	upgrader         *websocket.Upgrader
+	tls              *TLS
 }
 
 // URL /version/
@@ -34,6 +47,27 @@ func (h *Handler) version(w http.ResponseWriter, _ *http.Request) {
 	_ = json.NewEncoder(w).Encode(JSON{"version": version.VERSION})
 }
 
+// URL /healthz
+// the process is up
+func (h *Handler) healthz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(JSON{"status": "ok"})
+}
+
+// URL /readyz
+// the workloads are loaded, runtime daemon and core are reachable
+func (h *Handler) readyz(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := h.workloadsManager.Ready(req.Context()); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(JSON{"status": "not ready", "error": err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(JSON{"status": "ready"})
+}
+
 // URL /profile/
 func (h *Handler) profile(w http.ResponseWriter, _ *http.Request) {
 	r := JSON{}
@@ -46,49 +80,240 @@ func (h *Handler) profile(w http.ResponseWriter, _ *http.Request) {
 }
 
 // URL /log/
+// streams logs in chunks by default, /log/ws and websocket upgrade requests use websocket,
+// /log/sse and requests accepting text/event-stream use server-sent events
 func (h *Handler) log(w http.ResponseWriter, req *http.Request) {
-	app := req.URL.Query().Get("app")
-	if app == "" {
+	query, err := parseLogQuery(req)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	logger := log.WithFunc("log").WithField("path", "/log")
-	// fuck httpie
-	w.WriteHeader(http.StatusOK)
-	if hijack, ok := w.(http.Hijacker); ok {
-		conn, buf, err := hijack.Hijack()
+	logger := log.WithFunc("log").WithField("path", req.URL.Path)
+
+	switch {
+	case strings.HasPrefix(req.URL.Path, "/log/ws") || websocket.IsWebSocketUpgrade(req):
+		conn, err := h.upgrader.Upgrade(w, req, nil)
 		if err != nil {
-			logger.Error(req.Context(), err, "connect failed")
+			logger.Error(req.Context(), err, "websocket upgrade failed")
 			return
 		}
-		defer conn.Close()
-		h.workloadsManager.PullLog(req.Context(), app, buf)
+		stream := &wsStream{conn: conn}
+		defer stream.close()
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		_ = utils.Pool.Submit(func() {
+			defer cancel()
+			stream.readLoop()
+		})
+		h.pullLog(ctx, query, stream)
+	case strings.HasPrefix(req.URL.Path, "/log/sse") || strings.Contains(req.Header.Get("Accept"), "text/event-stream"):
+		stream, ok := newSSEStream(w)
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		h.pullLog(req.Context(), query, stream)
+		_ = stream.close()
+	default:
+		// fuck httpie
+		w.WriteHeader(http.StatusOK)
+		if hijack, ok := w.(http.Hijacker); ok {
+			conn, buf, err := hijack.Hijack()
+			if err != nil {
+				logger.Error(req.Context(), err, "connect failed")
+				return
+			}
+			defer conn.Close()
+			h.workloadsManager.PullLog(req.Context(), query, workload.NewChunkedLogStream(buf))
+		}
+	}
+}
+
+// URL /workloads/ and /workloads/:id
+// returns the states of workloads recorded by agent, including the last status and when it was checked and reported
+func (h *Handler) workloads(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ID := strings.TrimPrefix(req.URL.Path, "/workloads/")
+	if ID == "" {
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(h.workloadsManager.GetWorkloadStates())
+		return
+	}
+	state, ok := h.workloadsManager.GetWorkloadState(ID)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(JSON{"error": "workload not found"})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(state)
+}
+
+// URL /workloads/:id/check
+// checks the health of the workload and reports its status to core immediately
+func (h *Handler) check(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ID := req.URL.Query().Get(":id")
+	if _, err := h.workloadsManager.CheckManagedWorkload(req.Context(), ID); err != nil {
+		status := http.StatusInternalServerError
+		if err == common.ErrWorkloadNotFound {
+			status = http.StatusNotFound
+		}
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(JSON{"error": err.Error()})
+		return
+	}
+	state, _ := h.workloadsManager.GetWorkloadState(ID)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(state)
+}
+
+// URL /reconcile
+// checks and reports all workloads on this node, and attaches the logs of running workloads again if they are detached
+func (h *Handler) reconcile(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := h.workloadsManager.Reconcile(req.Context()); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(JSON{"error": err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(h.workloadsManager.GetWorkloadStates())
+}
+
+// auth requires the credentials of request to be granted the scope
+func (h *Handler) auth(scope string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// file permission of the socket is the access control of trusted unix sockets
+		if h.config.API.Socket.Trusted && isUnixConn(req.Context()) {
+			handler(w, req)
+			return
+		}
+		switch err := h.authenticator.Authorize(req.Header.Get("Authorization"), scope); err {
+		case nil:
+			handler(w, req)
+		case common.ErrUnauthenticated:
+			w.Header().Set("WWW-Authenticate", `Bearer, Basic realm="eru-agent"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}
+}
+
+// pullLog pulls logs with heartbeats, stops if the heartbeat fails
+func (h *Handler) pullLog(ctx context.Context, query *types.LogQuery, stream workload.LogStream) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	interval := h.config.API.Heartbeat
+	if interval <= 0 {
+		interval = defaultHeartbeat
+	}
+	// the heartbeat is waited for, so the stream is not written after returning
+	done := make(chan struct{})
+	heartbeat := func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := stream.Heartbeat(); err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}
+	if err := utils.Pool.Submit(heartbeat); err != nil {
+		close(done)
 	}
+	h.workloadsManager.PullLog(ctx, query, stream)
+	cancel()
+	<-done
+}
+
+// parseLogQuery parses the query parameters of /log/,
+// since can be RFC 3339 time, unix timestamp or duration before now like 10m
+func parseLogQuery(req *http.Request) (*types.LogQuery, error) {
+	values := req.URL.Query()
+	query := &types.LogQuery{
+		App:        values.Get("app"),
+		EntryPoint: values.Get("entrypoint"),
+		Ident:      values.Get("ident"),
+		ID:         values.Get("id"),
+		Type:       values.Get("type"),
+		Contains:   values.Get("contains"),
+	}
+	if query.App == "" {
+		return nil, errors.New("app is required")
+	}
+	if regex := values.Get("regex"); regex != "" {
+		re, err := regexp.Compile(regex)
+		if err != nil {
+			return nil, err
+		}
+		query.Regexp = re
+	}
+	if tail := values.Get("tail"); tail != "" {
+		n, err := strconv.Atoi(tail)
+		if err != nil || n < 0 {
+			return nil, errors.Errorf("invalid tail %s", tail)
+		}
+		query.Tail = n
+	}
+	if since := values.Get("since"); since != "" {
+		t, err := parseSince(since)
+		if err != nil {
+			return nil, err
+		}
+		query.Since = t
+	}
+	return query, nil
+}
+
+func parseSince(since string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, since); err == nil {
+		return t, nil
+	}
+	if seconds, err := strconv.ParseFloat(since, 64); err == nil {
+		return time.Unix(0, int64(seconds*float64(time.Second))), nil
+	}
+	if d, err := time.ParseDuration(since); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, errors.Errorf("invalid since %s", since)
 }
 
 // NewHandler new api http handler
-func NewHandler(config *types.Config, workloadsManager *workload.Manager) *Handler {
+// tls can be nil, then the api is served in plain http
+func NewHandler(config *types.Config, workloadsManager *workload.Manager, tls *TLS) *Handler {
 	return &Handler{
 		config:           config,
 		workloadsManager: workloadsManager,
+		authenticator:    NewAuthenticator(&config.API),
+		upgrader:         newUpgrader(config.API.AllowedOrigins),
+		tls:              tls,
 	}
 }
 
-// Serve start a api service
-// blocks by http.ListenAndServe
-// run this in a separated goroutine
-func (h *Handler) Serve() {
-	if h.config.API.Addr == "" {
-		return
-	}
-	logger := log.WithFunc("serve")
-
+// router returns the restful API router
+func (h *Handler) router() *pat.PatternServeMux {
 	restfulAPIServer := pat.New()
 	handlers := map[string]map[string]func(http.ResponseWriter, *http.Request){
 		"GET": {
-			"/profile/": h.profile,
-			"/version/": h.version,
-			"/log/":     h.log,
+			"/profile/":   h.auth(common.ScopeDebug, h.profile),
+			"/version/":   h.version,
+			"/healthz":    h.healthz,
+			"/readyz":     h.readyz,
+			"/log/":       h.auth(common.ScopeLogs, h.log),
+			"/workloads/": h.auth(common.ScopeWorkloads, h.workloads),
+		},
+		"POST": {
+			"/workloads/:id/check": h.auth(common.ScopeAdmin, h.check),
+			"/reconcile":           h.auth(common.ScopeAdmin, h.reconcile),
 		},
 	}
 
@@ -97,16 +322,91 @@ func (h *Handler) Serve() {
 			restfulAPIServer.Add(method, route, http.HandlerFunc(handler))
 		}
 	}
+	return restfulAPIServer
+}
+
+// Serve start a api service on Addr and Addrs, unix sockets are served without TLS
+// blocks until the servers stop, shuts down gracefully when ctx is done
+// run this in a separated goroutine
+func (h *Handler) Serve(ctx context.Context) {
+	addrs := h.config.API.Addrs
+	if h.config.API.Addr != "" {
+		addrs = append([]string{h.config.API.Addr}, addrs...)
+	}
+	if len(addrs) == 0 {
+		return
+	}
+	logger := log.WithFunc("serve")
+
+	listeners := make([]net.Listener, 0, len(addrs))
+	for _, addr := range addrs {
+		lis, err := Listen(addr, h.config.API.Socket)
+		if err != nil {
+			logger.Error(nil, err, "http api start failed") //nolint
+			for _, l := range listeners {
+				l.Close()
+			}
+			return
+		}
+		listeners = append(listeners, lis)
+		logger.Infof(nil, "http api started %s", addr) //nolint
+	}
 
-	http.Handle("/", restfulAPIServer)
-	http.Handle("/metrics", promhttp.Handler())
-	logger.Infof(nil, "http api started %s", h.config.API.Addr) //nolint
+	mux := http.NewServeMux()
+	mux.Handle("/", h.router())
+	mux.Handle("/metrics", h.auth(common.ScopeMetrics, promhttp.Handler().ServeHTTP))
+	// pprof handlers are registered in the default mux
+	mux.Handle("/debug/pprof/", h.auth(common.ScopeDebug, http.DefaultServeMux.ServeHTTP))
 
 	server := &http.Server{
-		Addr:              h.config.API.Addr,
+		Handler:           mux,
 		ReadHeaderTimeout: 3 * time.Second,
+		// requests are canceled when ctx is done, so log streams end before shutdown
+		BaseContext: func(net.Listener) context.Context { return ctx },
+		ConnContext: markUnixConn,
+	}
+	if h.tls != nil {
+		server.TLSConfig = h.tls.Config()
+		// log streams hijack connections or upgrade to websocket, which require HTTP/1.1
+		server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
 	}
-	if err := server.ListenAndServe(); err != nil {
-		logger.Error(nil, err, "http api start failed") //nolint
+	done := make(chan struct{})
+	_ = utils.Pool.Submit(func() {
+		defer close(done)
+		<-ctx.Done()
+		timeout := h.config.API.ShutdownTimeout
+		if timeout <= 0 {
+			timeout = defaultShutdownTimeout
+		}
+		utils.WithTimeout(context.TODO(), timeout, func(shutdownCtx context.Context) {
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				logger.Error(nil, err, "http api shutdown failed") //nolint
+			}
+		})
+	})
+
+	errChan := make(chan error, len(listeners))
+	for _, lis := range listeners {
+		lis := lis
+		_ = utils.Pool.Submit(func() {
+			if h.tls != nil && !IsUnix(lis.Addr()) {
+				errChan <- server.ServeTLS(lis, "", "")
+				return
+			}
+			errChan <- server.Serve(lis)
+		})
+	}
+	for range listeners {
+		if err := <-errChan; err != http.ErrServerClosed {
+			logger.Error(nil, err, "http api serve failed") //nolint
+		}
+	}
+	select {
+	case <-ctx.Done():
+		<-done
+	default:
+		// all listeners failed
+		return
 	}
+	logger.Info(nil, "http api stopped") //nolint
 }
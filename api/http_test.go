@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/manager/workload"
+	"github.com/projecteru2/agent/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHandler(t *testing.T) *Handler {
+	config := &types.Config{
+		HostName:                "fake",
+		Store:                   common.MocksStore,
+		Runtime:                 common.MocksRuntime,
+		HealthCheck:             types.HealthCheckConfig{Interval: 10, Timeout: 5, CacheTTL: 300},
+		GlobalConnectionTimeout: 10 * time.Millisecond,
+		API:                     types.APIConfig{Token: "nerv"},
+	}
+	manager, err := workload.NewManager(context.Background(), config)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = manager.Run(ctx) }()
+	return NewHandler(config, manager, nil)
+}
+
+func TestWorkloads(t *testing.T) {
+	h := newTestHandler(t)
+
+	router := h.router()
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer nerv")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Eventually(t, func() bool {
+		states := []*types.WorkloadState{}
+		w := get("/workloads/")
+		return w.Code == http.StatusOK && json.Unmarshal(w.Body.Bytes(), &states) == nil && len(states) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	w := get("/workloads/Shinji")
+	assert.Equal(t, http.StatusOK, w.Code)
+	state := &types.WorkloadState{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), state))
+	assert.Equal(t, "Shinji", state.ID)
+	assert.True(t, state.Status.Healthy)
+	assert.False(t, state.CheckedAt.IsZero())
+
+	assert.Equal(t, http.StatusNotFound, get("/workloads/Kaworu").Code)
+
+	// credentials are required once configured
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/workloads/", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestCheckAndReconcile(t *testing.T) {
+	h := newTestHandler(t)
+	router := h.router()
+	post := func(path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post("/workloads/Rei/check", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, post("/reconcile", "seele").Code)
+
+	w := post("/workloads/Rei/check", "nerv")
+	assert.Equal(t, http.StatusOK, w.Code)
+	state := &types.WorkloadState{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), state))
+	assert.Equal(t, "Rei", state.ID)
+	assert.True(t, state.Status.Running)
+	assert.False(t, state.ReportedAt.IsZero())
+	assert.Equal(t, http.StatusNotFound, post("/workloads/Kaworu/check", "nerv").Code)
+
+	w = post("/reconcile", "nerv")
+	assert.Equal(t, http.StatusOK, w.Code)
+	states := []*types.WorkloadState{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &states))
+	assert.Len(t, states, 3)
+
+	// disabled without token
+	h.authenticator = NewAuthenticator(&types.APIConfig{})
+	assert.Equal(t, http.StatusForbidden, post("/reconcile", "nerv").Code)
+}
+
+func TestHealthzAndReadyz(t *testing.T) {
+	h := newTestHandler(t)
+	router := h.router()
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get("/healthz"))
+	// ready after the workloads are loaded
+	assert.Eventually(t, func() bool { return get("/readyz") == http.StatusOK }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestServeShutdown(t *testing.T) {
+	h := newTestHandler(t)
+	h.config.API.Addr = "127.0.0.1:0"
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.Serve(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("api is not shut down")
+	}
+}
+
+func TestServeUnix(t *testing.T) {
+	h := newTestHandler(t)
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	h.config.API.Addr = "127.0.0.1:0"
+	h.config.API.Addrs = []string{"unix://" + path}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.Serve(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	get := func() int {
+		resp, err := client.Get("http://agent/workloads/")
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Eventually(t, func() bool { return get() == http.StatusUnauthorized }, 5*time.Second, 10*time.Millisecond)
+
+	// requests from trusted sockets skip auth
+	h.config.API.Socket.Trusted = true
+	assert.Equal(t, http.StatusOK, get())
+}
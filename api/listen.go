@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/projecteru2/agent/types"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	unixPrefix        = "unix://"
+	defaultSocketMode = "0660"
+)
+
+// Listen listens on the address, which is a tcp address or unix:///path/to/socket,
+// the mode and owner of the unix socket are set by config
+func Listen(addr string, config types.APISocketConfig) (net.Listener, error) {
+	if !strings.HasPrefix(addr, unixPrefix) {
+		return net.Listen("tcp", addr)
+	}
+	path := strings.TrimPrefix(addr, unixPrefix)
+	// remove the socket left by the last run
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := setSocketPermission(path, config); err != nil {
+		lis.Close()
+		return nil, err
+	}
+	return lis, nil
+}
+
+func setSocketPermission(path string, config types.APISocketConfig) error {
+	mode := config.Mode
+	if mode == "" {
+		mode = defaultSocketMode
+	}
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return errors.Errorf("invalid socket mode %s", mode)
+	}
+	if err := os.Chmod(path, os.FileMode(perm)); err != nil {
+		return err
+	}
+	if config.Owner == "" {
+		return nil
+	}
+	uid, gid, err := lookupOwner(config.Owner)
+	if err != nil {
+		return err
+	}
+	return os.Chown(path, uid, gid)
+}
+
+// lookupOwner parses user:group, both can be names or ids, -1 means unchanged
+func lookupOwner(owner string) (int, int, error) {
+	username, group, _ := strings.Cut(owner, ":")
+	uid, gid := -1, -1
+	if username != "" {
+		id, err := strconv.Atoi(username)
+		if err != nil {
+			u, err := user.Lookup(username)
+			if err != nil {
+				return 0, 0, err
+			}
+			if id, err = strconv.Atoi(u.Uid); err != nil {
+				return 0, 0, err
+			}
+		}
+		uid = id
+	}
+	if group != "" {
+		id, err := strconv.Atoi(group)
+		if err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return 0, 0, err
+			}
+			if id, err = strconv.Atoi(g.Gid); err != nil {
+				return 0, 0, err
+			}
+		}
+		gid = id
+	}
+	return uid, gid, nil
+}
+
+// IsUnix returns whether the address is of a unix socket
+func IsUnix(addr net.Addr) bool {
+	return addr != nil && addr.Network() == "unix"
+}
+
+type unixConnKey struct{}
+
+// markUnixConn marks the requests from unix socket, which are trusted if configured
+func markUnixConn(ctx context.Context, conn net.Conn) context.Context {
+	if IsUnix(conn.LocalAddr()) {
+		return context.WithValue(ctx, unixConnKey{}, true)
+	}
+	return ctx
+}
+
+func isUnixConn(ctx context.Context) bool {
+	v, _ := ctx.Value(unixConnKey{}).(bool)
+	return v
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/projecteru2/agent/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListen(t *testing.T) {
+	lis, err := Listen("127.0.0.1:0", types.APISocketConfig{})
+	assert.NoError(t, err)
+	assert.False(t, IsUnix(lis.Addr()))
+	lis.Close()
+
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	owner := fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())
+	lis, err = Listen("unix://"+path, types.APISocketConfig{Mode: "0600", Owner: owner})
+	assert.NoError(t, err)
+	assert.True(t, IsUnix(lis.Addr()))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	lis.Close()
+
+	// the stale socket is replaced, with the default mode
+	lis, err = Listen("unix://"+path, types.APISocketConfig{})
+	assert.NoError(t, err)
+	info, err = os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0660), info.Mode().Perm())
+	lis.Close()
+
+	_, err = Listen("unix://"+path, types.APISocketConfig{Mode: "rw"})
+	assert.Error(t, err)
+	_, err = Listen("unix://"+path, types.APISocketConfig{Owner: "no-such-user-of-nerv"})
+	assert.Error(t, err)
+
+	// only sockets are removed
+	file := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, os.WriteFile(file, nil, 0600))
+	_, err = Listen("unix://"+file, types.APISocketConfig{})
+	assert.Error(t, err)
+}
+
+func TestLookupOwner(t *testing.T) {
+	uid, gid, err := lookupOwner("root:0")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, uid)
+	assert.Equal(t, 0, gid)
+	uid, gid, err = lookupOwner(":1000")
+	assert.NoError(t, err)
+	assert.Equal(t, -1, uid)
+	assert.Equal(t, 1000, gid)
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultHeartbeat       = 30 * time.Second
+	defaultShutdownTimeout = 10 * time.Second
+	wsWriteTimeout         = 10 * time.Second
+)
+
+// newUpgrader accepts websocket requests from the same origin, or one of the allowed origins,
+// so other sites can't open log streams with the credentials cached by browsers.
+// "*" allows all origins
+func newUpgrader(allowedOrigins []string) *websocket.Upgrader {
+	if len(allowedOrigins) == 0 {
+		// the default check of websocket is same origin
+		return &websocket.Upgrader{}
+	}
+	allowed := map[string]bool{}
+	for _, origin := range allowedOrigins {
+		allowed[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+	return &websocket.Upgrader{
+		CheckOrigin: func(req *http.Request) bool {
+			origin := req.Header.Get("Origin")
+			if origin == "" || allowed["*"] || allowed[strings.ToLower(origin)] {
+				return true
+			}
+			u, err := url.Parse(origin)
+			return err == nil && strings.EqualFold(u.Host, req.Host)
+		},
+	}
+}
+
+// sseStream writes logs as server-sent events
+type sseStream struct {
+	sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newSSEStream(w http.ResponseWriter) (*sseStream, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &sseStream{w: w, flusher: flusher}, true
+}
+
+func (s *sseStream) Write(data []byte) error {
+	return s.send("data: %s\n\n", data)
+}
+
+// Heartbeat sends a comment, which is ignored by clients
+func (s *sseStream) Heartbeat() error {
+	return s.send(": heartbeat\n\n")
+}
+
+// close tells the client the stream ends, so it won't reconnect
+func (s *sseStream) close() error {
+	return s.send("event: close\ndata: \n\n")
+}
+
+func (s *sseStream) send(format string, args ...interface{}) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, err := fmt.Fprintf(s.w, format, args...); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// wsStream writes logs as websocket text messages
+type wsStream struct {
+	sync.Mutex
+	conn *websocket.Conn
+}
+
+func (s *wsStream) Write(data []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	_ = s.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return s.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// Heartbeat sends a ping, the client answers it with a pong automatically
+func (s *wsStream) Heartbeat() error {
+	return s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+}
+
+// close sends a close message before closing the connection
+func (s *wsStream) close() error {
+	_ = s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(wsWriteTimeout))
+	return s.conn.Close()
+}
+
+// readLoop handles control messages from the client, and returns when the client closes
+func (s *wsStream) readLoop() {
+	for {
+		if _, _, err := s.conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
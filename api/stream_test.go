@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSEStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		stream, ok := newSSEStream(w)
+		assert.True(t, ok)
+		assert.NoError(t, stream.Write([]byte(`{"data":"rei"}`)))
+		assert.NoError(t, stream.Heartbeat())
+		assert.NoError(t, stream.close())
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.Equal(t, `data: {"data":"rei"}||: heartbeat||event: close|data: |`, strings.Join(lines, "|"))
+}
+
+func TestWSStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := newUpgrader(nil).Upgrade(w, req, nil)
+		assert.NoError(t, err)
+		stream := &wsStream{conn: conn}
+		assert.NoError(t, stream.Heartbeat())
+		assert.NoError(t, stream.Write([]byte(`{"data":"rei"}`)))
+		assert.NoError(t, stream.close())
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	pinged := false
+	conn.SetPingHandler(func(string) error {
+		pinged = true
+		return nil
+	})
+
+	typ, data, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, websocket.TextMessage, typ)
+	assert.Equal(t, `{"data":"rei"}`, string(data))
+	assert.True(t, pinged)
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+}
+
+func TestUpgraderOrigin(t *testing.T) {
+	dial := func(allowedOrigins []string, origin string) error {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if conn, err := newUpgrader(allowedOrigins).Upgrade(w, req, nil); err == nil {
+				conn.Close()
+			}
+		}))
+		defer server.Close()
+		header := http.Header{}
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+
+	// same origin by default
+	assert.NoError(t, dial(nil, ""))
+	assert.Error(t, dial(nil, "https://seele.org"))
+
+	allowed := []string{"https://nerv.org/"}
+	assert.NoError(t, dial(allowed, "https://NERV.org"))
+	assert.Error(t, dial(allowed, "https://seele.org"))
+	assert.Error(t, dial(allowed, "http://nerv.org"))
+	assert.NoError(t, dial([]string{"*"}, "https://seele.org"))
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"sync/atomic"
+
+	"github.com/projecteru2/agent/types"
+
+	"github.com/pkg/errors"
+)
+
+// TLS serves the certificate of api, which can be reloaded without restarting
+type TLS struct {
+	config    types.APITLSConfig
+	cert      atomic.Pointer[tls.Certificate]
+	tlsConfig *tls.Config
+}
+
+// NewTLS loads the certificate and client CA, returns nil if tls is not configured.
+// Client certificates are required if client CA is configured.
+func NewTLS(config types.APITLSConfig) (*TLS, error) {
+	if config.Cert == "" && config.Key == "" {
+		return nil, nil
+	}
+	t := &TLS{config: config}
+	if err := t.Reload(); err != nil {
+		return nil, err
+	}
+	t.tlsConfig = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return t.cert.Load(), nil
+		},
+	}
+	if config.ClientCA != "" {
+		ca, err := os.ReadFile(config.ClientCA)
+		if err != nil {
+			return nil, err
+		}
+		t.tlsConfig.ClientCAs = x509.NewCertPool()
+		if !t.tlsConfig.ClientCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.Errorf("no certificate found in %s", config.ClientCA)
+		}
+		t.tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return t, nil
+}
+
+// Reload reloads the certificate, new connections will use the new one.
+// If it fails, the old one is kept.
+func (t *TLS) Reload() error {
+	cert, err := tls.LoadX509KeyPair(t.config.Cert, t.config.Key)
+	if err != nil {
+		return err
+	}
+	t.cert.Store(&cert)
+	return nil
+}
+
+// Config returns the tls config for servers
+func (t *TLS) Config() *tls.Config {
+	return t.tlsConfig
+}
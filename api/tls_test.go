@@ -0,0 +1,103 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/projecteru2/agent/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestCert writes a certificate signed by parent, or a self-signed CA if parent is nil
+func newTestCert(t *testing.T, dir, cn string, parent *tls.Certificate) (string, string, *tls.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{cn},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, interface{}(key)
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	certPath, keyPath := filepath.Join(dir, cn+".crt"), filepath.Join(dir, cn+".key")
+	assert.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certPath, keyPath, &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestTLS(t *testing.T) {
+	s, err := NewTLS(types.APITLSConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, s)
+
+	dir := t.TempDir()
+	caPath, _, ca := newTestCert(t, dir, "ca", nil)
+	certPath, keyPath, _ := newTestCert(t, dir, "agent", ca)
+	_, _, client := newTestCert(t, dir, "client", ca)
+
+	_, err = NewTLS(types.APITLSConfig{Cert: certPath, Key: filepath.Join(dir, "missing.key")})
+	assert.Error(t, err)
+	s, err = NewTLS(types.APITLSConfig{Cert: certPath, Key: keyPath, ClientCA: caPath})
+	assert.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := &http.Server{
+		Handler:           http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }),
+		TLSConfig:         s.Config(),
+		ReadHeaderTimeout: time.Second,
+		ErrorLog:          log.New(io.Discard, "", 0),
+	}
+	go func() { _ = server.ServeTLS(lis, "", "") }()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	get := func(certificates []tls.Certificate) error {
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certificates, ServerName: "agent"}}}
+		resp, err := c.Get("https://" + lis.Addr().String())
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	// client certificate is required
+	assert.Error(t, get(nil))
+	assert.NoError(t, get([]tls.Certificate{*client}))
+
+	// the old certificate is kept if reloading fails
+	assert.NoError(t, os.WriteFile(keyPath, []byte("broken"), 0600))
+	assert.Error(t, s.Reload())
+	assert.NoError(t, get([]tls.Certificate{*client}))
+}
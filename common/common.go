@@ -35,8 +35,42 @@ const (
 	// MocksKV use the mock KV
 	MocksKV = "mocks"
 
+	// BroadcastPolicyDrop drops logs for the subscriber whose queue is full
+	BroadcastPolicyDrop = "drop"
+	// BroadcastPolicyEvict evicts the subscriber whose queue is full
+	BroadcastPolicyEvict = "evict"
+
+	// ScopeLogs is the api scope of log streams
+	ScopeLogs = "logs"
+	// ScopeMetrics is the api scope of prometheus metrics
+	ScopeMetrics = "metrics"
+	// ScopeDebug is the api scope of pprof
+	ScopeDebug = "debug"
+	// ScopeWorkloads is the api scope of reading workload states
+	ScopeWorkloads = "workloads"
+	// ScopeAdmin is the api scope of triggering checks, it always requires credentials
+	ScopeAdmin = "admin"
+	// ScopeAll grants all scopes
+	ScopeAll = "*"
+
 	// ERUNodeName key of workload's name label
 	ERUNodeName = "eru.nodename"
 	// ERUCoreID key of workload's core ID label
 	ERUCoreID = "eru.coreid"
+	// ERULogMultiline key of workload's label, which picks the multiline rule of logs
+	ERULogMultiline = "eru.log.multiline"
+	// ERULogParser key of workload's label, which picks the format of logs: json, logfmt or auto
+	ERULogParser = "eru.log.parser"
+	// ERULogPromote key of workload's label, which overrides the keys to promote, separated by comma
+	ERULogPromote = "eru.log.promote"
+	// ERULogRate key of workload's label, which overrides the rate limit of logs in lines per second
+	ERULogRate = "eru.log.rate"
+	// ERULogBurst key of workload's label, which overrides the burst of log rate limit
+	ERULogBurst = "eru.log.burst"
+	// ERULogRateMode key of workload's label, which overrides the mode of log rate limit: drop or sample
+	ERULogRateMode = "eru.log.rate_mode"
+	// ERUHealthCheckInterval key of workload's label, which overrides the interval of health check in seconds
+	ERUHealthCheckInterval = "eru.healthcheck.interval"
+	// ERUHealthCheckTimeout key of workload's label, which overrides the timeout of health check in seconds
+	ERUHealthCheckTimeout = "eru.healthcheck.timeout"
 )
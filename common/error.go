@@ -7,6 +7,38 @@ var (
 	ErrNotImplemented = errors.New("not implemented")
 	// ErrConnecting means writer is in connecting status, waiting to be connected
 	ErrConnecting = errors.New("connecting")
+	// ErrSpoolFull means the log spool reaches its size limit, the log line is dropped
+	ErrSpoolFull = errors.New("spool full")
+	// ErrQueueFull means the queue of an async encoder is full, the log line is not queued
+	ErrQueueFull = errors.New("queue full")
+	// ErrEncoderClosed means the shared encoder is stopped by another writer, the log line is not queued
+	ErrEncoderClosed = errors.New("encoder closed")
+	// ErrMultilineNotFound means the multiline rule picked by label is not defined
+	ErrMultilineNotFound = errors.New("multiline rule not found")
+	// ErrInvalidParser means the log format picked by label is not supported
+	ErrInvalidParser = errors.New("invalid log parser")
+	// ErrInvalidRateLimit means the log rate limit is invalid
+	ErrInvalidRateLimit = errors.New("invalid log rate limit")
+	// ErrInvalidBroadcastPolicy means the log broadcast policy is neither drop nor evict
+	ErrInvalidBroadcastPolicy = errors.New("invalid log broadcast policy")
+	// ErrSubscriberEvicted means the log subscriber is too slow to keep up
+	ErrSubscriberEvicted = errors.New("log subscriber evicted because it's too slow")
+	// ErrUnauthenticated means the api request has no valid credentials
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrInvalidAnonymousScope means admin or all scopes are allowed without credentials, admin always requires credentials
+	ErrInvalidAnonymousScope = errors.New("invalid anonymous scope")
+	// ErrPermissionDenied means the credentials of api request are not granted the scope
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrWorkloadsNotLoaded means the workloads are not loaded yet when starting
+	ErrWorkloadsNotLoaded = errors.New("workloads not loaded")
+	// ErrRuntimeUnreachable means the runtime daemon is unreachable
+	ErrRuntimeUnreachable = errors.New("runtime daemon unreachable")
+	// ErrCoreUnreachable means core is unreachable
+	ErrCoreUnreachable = errors.New("core unreachable")
+	// ErrWorkloadNotFound means the workload doesn't exist, or it's not managed by this agent
+	ErrWorkloadNotFound = errors.New("workload not found")
+	// ErrExecNotExited means the command of health check is still running after its output is closed
+	ErrExecNotExited = errors.New("exec not exited")
 	// ErrInvalidScheme .
 	ErrInvalidScheme = errors.New("invalid scheme")
 	// ErrGetRuntimeFailed .
@@ -0,0 +1,165 @@
+package logs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/types"
+	"github.com/projecteru2/agent/utils"
+
+	coreutils "github.com/projecteru2/core/utils"
+)
+
+// asyncEncoder sends logs in background, the writer spools the logs it didn't deliver,
+// so they are replayed in order after reconnecting
+type asyncEncoder interface {
+	Encoder
+	// Drain stops sending without flushing the pending logs, returns the logs not delivered in order
+	Drain() []*types.Log
+	// Deliver sends the logs synchronously, it's used to replay spooled logs
+	Deliver(loglines []*types.Log) error
+	// BatchSize is the max lines of a batch
+	BatchSize() int
+}
+
+// batchSender sends a batch, returns the logs not delivered if it fails,
+// it should give up when ctx is done
+type batchSender func(ctx context.Context, batch []*types.Log) ([]*types.Log, error)
+
+// batcher queues logs in a bounded queue, and sends them in batches in background.
+// A failed delivery stops sending, it's returned by the next Encode,
+// the failed logs and the ones queued after them are kept in order, until they are drained.
+type batcher struct {
+	size    int
+	bytes   int
+	wait    time.Duration
+	timeout time.Duration
+	send    batchSender
+
+	lines     chan *types.Log
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+	err       deliveryError
+
+	// undelivered is written by run, and read after done
+	undelivered []*types.Log
+}
+
+func newBatcher(size, bytes int, wait time.Duration, queueSize int, timeout time.Duration, send batchSender) (*batcher, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &batcher{
+		size:    size,
+		bytes:   bytes,
+		wait:    wait,
+		timeout: timeout,
+		send:    send,
+		lines:   make(chan *types.Log, queueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	if err := utils.Pool.Submit(b.run); err != nil {
+		cancel()
+		return nil, err
+	}
+	return b, nil
+}
+
+// Encode queues the log without blocking, returns ErrQueueFull if the queue is full
+func (b *batcher) Encode(logline *types.Log) error {
+	if err := b.err.get(); err != nil {
+		return err
+	}
+	select {
+	case b.lines <- logline:
+		return nil
+	default:
+		return common.ErrQueueFull
+	}
+}
+
+// Close sends the pending logs and stops, the logs failed to send can be drained after
+func (b *batcher) Close() error {
+	b.closeOnce.Do(func() { close(b.lines) })
+	<-b.done
+	return nil
+}
+
+// Drain stops sending without flushing the pending logs, returns the logs not delivered in order.
+// The batch being sent is given up, it's returned too.
+func (b *batcher) Drain() []*types.Log {
+	b.cancel()
+	b.closeOnce.Do(func() { close(b.lines) })
+	<-b.done
+	return b.undelivered
+}
+
+// Deliver sends the logs synchronously in batches, stops at the first failure
+func (b *batcher) Deliver(loglines []*types.Log) error {
+	for len(loglines) > 0 {
+		n := coreutils.Min(len(loglines), b.size)
+		if err := b.deliver(loglines[:n]); err != nil {
+			return err
+		}
+		loglines = loglines[n:]
+	}
+	return nil
+}
+
+// BatchSize .
+func (b *batcher) BatchSize() int {
+	return b.size
+}
+
+func (b *batcher) deliver(batch []*types.Log) error {
+	ctx, cancel := context.WithTimeout(b.ctx, b.timeout)
+	defer cancel()
+	_, err := b.send(ctx, batch)
+	return err
+}
+
+func (b *batcher) run() {
+	defer close(b.done)
+	timer := time.NewTimer(b.wait)
+	defer timer.Stop()
+
+	var batch []*types.Log
+	size := 0
+	flush := func() {
+		switch {
+		case len(batch) == 0:
+		case b.err.get() != nil || b.ctx.Err() != nil:
+			// the remote is known to be failing, or it's draining, keep the logs in order
+			b.undelivered = append(b.undelivered, batch...)
+		default:
+			if failed, err := b.send(b.ctx, batch); err != nil {
+				b.err.set(err)
+				b.undelivered = append(b.undelivered, failed...)
+			}
+		}
+		batch = nil
+		size = 0
+		timer.Reset(b.wait)
+	}
+
+	for {
+		select {
+		case logline, ok := <-b.lines:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, logline)
+			size += len(logline.Data)
+			if len(batch) >= b.size || size >= b.bytes {
+				flush()
+			}
+		case <-timer.C:
+			flush()
+		}
+	}
+}
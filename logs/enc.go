@@ -1,10 +1,16 @@
 package logs
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/projecteru2/agent/common"
 	"github.com/projecteru2/agent/types"
@@ -18,6 +24,25 @@ type Encoder interface {
 	Close() error
 }
 
+// deliveryError keeps the error of background deliveries,
+// encoders sending logs asynchronously return it on the next Encode
+type deliveryError struct {
+	sync.RWMutex
+	err error
+}
+
+func (d *deliveryError) get() error {
+	d.RLock()
+	defer d.RUnlock()
+	return d.err
+}
+
+func (d *deliveryError) set(err error) {
+	d.Lock()
+	defer d.Unlock()
+	d.err = err
+}
+
 // StreamEncoder .
 type StreamEncoder struct {
 	*json.Encoder
@@ -42,6 +67,135 @@ func (e *StreamEncoder) Close() error {
 	return e.wt.Close()
 }
 
+const (
+	// syslogFacility is user-level messages
+	syslogFacility = 1
+	// syslogSDID is the SD-ID of structured data, 32473 is the enterprise number reserved for documentation
+	syslogSDID      = "eru@32473"
+	syslogTimestamp = "2006-01-02T15:04:05.999999Z07:00"
+)
+
+var syslogParamValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// SyslogEncoder encodes logs into RFC 5424 frames
+type SyslogEncoder struct {
+	wt            io.WriteCloser
+	hostname      string
+	octetCounting bool
+}
+
+// NewSyslogEncoder .
+// octetCounting should be true for stream transports, see RFC 6587
+func NewSyslogEncoder(wt io.WriteCloser, octetCounting bool) *SyslogEncoder {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+	return &SyslogEncoder{
+		wt:            wt,
+		hostname:      hostname,
+		octetCounting: octetCounting,
+	}
+}
+
+// Encode .
+// APP-NAME is Name, PROCID is Ident, MSGID is ID, severity is err for stderr and info for others,
+// other fields and Extra go to structured data.
+func (e *SyslogEncoder) Encode(logline *types.Log) error {
+	severity := 6
+	if logline.Type == "stderr" {
+		severity = 3
+	}
+	timestamp := time.Now()
+	if t, err := time.ParseInLocation(common.DateTimeFormat, logline.Datetime, time.Local); err == nil {
+		timestamp = t
+	}
+	hostname := e.hostname
+	if nodename := logline.Extra["nodename"]; nodename != "" {
+		hostname = nodename
+	}
+
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "<%d>1 %s %s %s %s %s ",
+		syslogFacility*8+severity,
+		timestamp.Format(syslogTimestamp),
+		syslogHeaderField(hostname, 255),
+		syslogHeaderField(logline.Name, 48),
+		syslogHeaderField(logline.Ident, 128),
+		syslogHeaderField(logline.ID, 32),
+	)
+	e.writeStructuredData(msg, logline)
+	if logline.Data != "" {
+		msg.WriteByte(' ')
+		msg.WriteString(logline.Data)
+	}
+
+	frame := msg.Bytes()
+	if e.octetCounting {
+		frame = append([]byte(strconv.Itoa(len(frame))+" "), frame...)
+	}
+	_, err := e.wt.Write(frame)
+	return err
+}
+
+func (e *SyslogEncoder) writeStructuredData(msg *bytes.Buffer, logline *types.Log) {
+	params := map[string]string{
+		"id":         logline.ID,
+		"type":       logline.Type,
+		"entrypoint": logline.EntryPoint,
+		"ident":      logline.Ident,
+	}
+	for key, value := range logline.Extra {
+		params[key] = value
+	}
+	keys := make([]string, 0, len(params))
+	for key, value := range params {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	msg.WriteString("[" + syslogSDID)
+	for _, key := range keys {
+		fmt.Fprintf(msg, ` %s="%s"`, syslogParamName(key), syslogParamValueEscaper.Replace(params[key]))
+	}
+	msg.WriteByte(']')
+}
+
+// Close .
+func (e *SyslogEncoder) Close() error {
+	return e.wt.Close()
+}
+
+// syslogHeaderField keeps printable US-ASCII only, and truncates to the max length
+func syslogHeaderField(field string, maxLen int) string {
+	field = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, field)
+	if field == "" {
+		return "-"
+	}
+	if len(field) > maxLen {
+		field = field[:maxLen]
+	}
+	return field
+}
+
+// syslogParamName is a header field without '=', ']' and '"'
+func syslogParamName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, name)
+	return syslogHeaderField(name, 32)
+}
+
 // JournalEncoder .
 type JournalEncoder struct {
 	sync.Mutex
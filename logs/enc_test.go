@@ -0,0 +1,52 @@
+package logs
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/projecteru2/agent/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (b *bufferCloser) Close() error {
+	return nil
+}
+
+func TestSyslogEncoder(t *testing.T) {
+	logline := &types.Log{
+		ID:         "Rei",
+		Name:       "nerv",
+		Type:       "stderr",
+		EntryPoint: "eva0",
+		Ident:      "boiled",
+		Data:       "data",
+		Datetime:   "2023-10-11 12:13:14.123456",
+		Extra:      map[string]string{"nodename": "tokyo3", "path": `C:\\magi]"`},
+	}
+
+	buf := &bufferCloser{}
+	enc := NewSyslogEncoder(buf, false)
+	assert.NoError(t, enc.Encode(logline))
+	frame := buf.String()
+	assert.Regexp(t, `^<11>1 2023-10-11T12:13:14\.123456(Z|[+-][0-9:]+) tokyo3 nerv boiled Rei `, frame)
+	assert.Contains(t, frame, `[eru@32473 entrypoint="eva0" id="Rei" ident="boiled" nodename="tokyo3" path="C:\\\\magi\]\"" type="stderr"] data`)
+
+	// octet counting, and info for stdout
+	buf = &bufferCloser{}
+	enc = NewSyslogEncoder(buf, true)
+	logline.Type = "stdout"
+	logline.Name = "nerv headquarters"
+	logline.Extra = nil
+	assert.NoError(t, enc.Encode(logline))
+	frame = buf.String()
+	assert.Regexp(t, `^[0-9]+ <14>1 \S+ \S+ nerv_headquarters boiled Rei \[eru@32473 `, frame)
+	length, msg, _ := strings.Cut(frame, " ")
+	assert.Equal(t, strconv.Itoa(len(msg)), length)
+}
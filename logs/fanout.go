@@ -0,0 +1,56 @@
+package logs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/projecteru2/agent/types"
+
+	"github.com/projecteru2/core/log"
+)
+
+// FanoutWriter writes the same log to several writers
+type FanoutWriter struct {
+	writers []*Writer
+}
+
+// NewFanoutWriter returns a writer for the forwards,
+// forwards failed to create are skipped, fails only if none of them can be created
+func NewFanoutWriter(ctx context.Context, addrs []string, config *types.LogConfig) (*FanoutWriter, error) {
+	if len(addrs) == 0 {
+		addrs = []string{Discard}
+	}
+	logger := log.WithFunc("NewFanoutWriter")
+
+	// only the first writer writes to stdout, or we will see the log several times
+	stdout := config.Stdout
+	f := &FanoutWriter{}
+	var err error
+	for _, addr := range addrs {
+		c := *config
+		c.Stdout = stdout
+		writer, e := NewWriter(ctx, addr, &c)
+		if e != nil {
+			logger.Errorf(ctx, e, "create log forward %s failed", addr)
+			err = e
+			continue
+		}
+		stdout = false
+		f.writers = append(f.writers, writer)
+	}
+	if len(f.writers) == 0 {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write writes log to all writers, a failed writer won't stop the others
+func (f *FanoutWriter) Write(logline *types.Log) error {
+	var errs []error
+	for _, writer := range f.writers {
+		if err := writer.Write(logline); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,190 @@
+package logs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/types"
+
+	"github.com/pkg/errors"
+	"github.com/projecteru2/core/log"
+)
+
+const (
+	defaultHTTPBatchSize  = 1000
+	defaultHTTPBatchBytes = 1024 * 1024
+	defaultHTTPBatchWait  = time.Second
+	defaultHTTPQueueSize  = 10000
+	defaultHTTPTimeout    = 10 * time.Second
+	defaultHTTPMinBackoff = 500 * time.Millisecond
+	defaultHTTPMaxBackoff = 30 * time.Second
+)
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type lokiPush struct {
+	Streams []*lokiStream `json:"streams"`
+}
+
+// HTTPEncoder pushes logs to a Loki style HTTP API in batches.
+// Batches are sent in background, a failed delivery is returned by the next Encode,
+// the logs not delivered can be drained, so they are spooled instead of lost.
+type HTTPEncoder struct {
+	*batcher
+	url    string
+	client *http.Client
+	config types.LogHTTPConfig
+}
+
+// NewHTTPEncoder .
+func NewHTTPEncoder(url string, client *http.Client, config types.LogHTTPConfig) (*HTTPEncoder, error) {
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultHTTPBatchSize
+	}
+	if config.BatchBytes <= 0 {
+		config.BatchBytes = defaultHTTPBatchBytes
+	}
+	if config.BatchWait <= 0 {
+		config.BatchWait = defaultHTTPBatchWait
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultHTTPQueueSize
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultHTTPTimeout
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaultHTTPMinBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultHTTPMaxBackoff
+	}
+
+	e := &HTTPEncoder{
+		url:    url,
+		client: client,
+		config: config,
+	}
+	var err error
+	if e.batcher, err = newBatcher(config.BatchSize, config.BatchBytes, config.BatchWait, config.QueueSize, config.Timeout, e.send); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// send pushes the batch, retries with backoff on 429, 5xx and network errors,
+// returns the batch if it's not delivered
+func (e *HTTPEncoder) send(ctx context.Context, batch []*types.Log) ([]*types.Log, error) {
+	logger := log.WithFunc("send").WithField("url", e.url)
+	body, err := e.marshal(batch)
+	if err != nil {
+		logger.Error(nil, err, "failed to marshal logs") //nolint
+		return nil, nil
+	}
+
+	backoff := e.config.MinBackoff
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := e.post(ctx, body)
+		if err == nil {
+			return nil, nil
+		}
+		if retryAfter < 0 || attempt >= e.config.MaxRetries || ctx.Err() != nil {
+			logger.Errorf(nil, err, "failed to push %d lines", len(batch)) //nolint
+			return batch, err
+		}
+		if retryAfter < backoff {
+			retryAfter = backoff
+		}
+		if retryAfter > e.config.MaxBackoff {
+			retryAfter = e.config.MaxBackoff
+		}
+		logger.Warnf(nil, "failed to push logs: %s, will retry after %v", err, retryAfter) //nolint
+		select {
+		case <-time.After(retryAfter):
+		case <-ctx.Done():
+			return batch, ctx.Err()
+		}
+		if backoff *= 2; backoff > e.config.MaxBackoff {
+			backoff = e.config.MaxBackoff
+		}
+	}
+}
+
+// post returns how long to wait before retrying, negative means not retryable
+func (e *HTTPEncoder) post(ctx context.Context, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return -1, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	for key, value := range e.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return 0, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		retryAfter := time.Duration(0)
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return retryAfter, errors.Errorf("unexpected status %d", resp.StatusCode)
+	default:
+		return -1, errors.Errorf("unexpected status %d", resp.StatusCode)
+	}
+}
+
+// marshal groups logs into streams by app, entrypoint, ident and nodename, then gzips them
+func (e *HTTPEncoder) marshal(batch []*types.Log) ([]byte, error) {
+	push := &lokiPush{}
+	streams := map[[4]string]*lokiStream{}
+	for _, logline := range batch {
+		key := [4]string{logline.Name, logline.EntryPoint, logline.Ident, logline.Extra["nodename"]}
+		stream, ok := streams[key]
+		if !ok {
+			stream = &lokiStream{Stream: map[string]string{}}
+			for i, label := range []string{"app", "entrypoint", "ident", "nodename"} {
+				if key[i] != "" {
+					stream.Stream[label] = key[i]
+				}
+			}
+			streams[key] = stream
+			push.Streams = append(push.Streams, stream)
+		}
+
+		timestamp := time.Now()
+		if t, err := time.ParseInLocation(common.DateTimeFormat, logline.Datetime, time.Local); err == nil {
+			timestamp = t
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(timestamp.UnixNano(), 10), logline.Data})
+	}
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	if err := json.NewEncoder(gz).Encode(push); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
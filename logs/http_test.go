@@ -0,0 +1,228 @@
+package logs
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type lokiServer struct {
+	sync.Mutex
+	*httptest.Server
+	pushes   []*lokiPush
+	failures int
+}
+
+func newLokiServer(t *testing.T, failures int) *lokiServer {
+	s := &lokiServer{failures: failures}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Lock()
+		defer s.Unlock()
+		if s.failures > 0 {
+			s.failures--
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "tenant", r.Header.Get("X-Scope-OrgID"))
+		gz, err := gzip.NewReader(r.Body)
+		assert.NoError(t, err)
+		push := &lokiPush{}
+		assert.NoError(t, json.NewDecoder(gz).Decode(push))
+		s.pushes = append(s.pushes, push)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	return s
+}
+
+func (s *lokiServer) getPushes() []*lokiPush {
+	s.Lock()
+	defer s.Unlock()
+	return s.pushes
+}
+
+func TestHTTPEncoder(t *testing.T) {
+	server := newLokiServer(t, 1)
+	defer server.Close()
+
+	enc, err := NewHTTPEncoder(server.URL+"/loki/api/v1/push", server.Client(), types.LogHTTPConfig{
+		BatchSize:  3,
+		BatchWait:  time.Hour,
+		MaxRetries: 1,
+		MinBackoff: time.Millisecond,
+		Headers:    map[string]string{"X-Scope-OrgID": "tenant"},
+	})
+	assert.NoError(t, err)
+	rei := &types.Log{Name: "nerv", EntryPoint: "eva0", Ident: "boiled", Data: "0", Datetime: "2023-10-11 12:13:14.123456", Extra: map[string]string{"nodename": "tokyo3"}}
+	shinji := &types.Log{Name: "nerv", EntryPoint: "eva1", Data: "1"}
+	for _, logline := range []*types.Log{rei, shinji, rei, shinji} {
+		assert.NoError(t, enc.Encode(logline))
+	}
+	// the first batch is sent after retrying, the last line is sent on closing
+	assert.NoError(t, enc.Close())
+
+	pushes := server.getPushes()
+	assert.Len(t, pushes, 2)
+	assert.Len(t, pushes[0].Streams, 2)
+	assert.Equal(t, map[string]string{"app": "nerv", "entrypoint": "eva0", "ident": "boiled", "nodename": "tokyo3"}, pushes[0].Streams[0].Stream)
+	assert.Len(t, pushes[0].Streams[0].Values, 2)
+	ts := time.Date(2023, 10, 11, 12, 13, 14, 123456000, time.Local).UnixNano()
+	assert.Equal(t, [2]string{strconv.FormatInt(ts, 10), "0"}, pushes[0].Streams[0].Values[0])
+	assert.Equal(t, map[string]string{"app": "nerv", "entrypoint": "eva1"}, pushes[0].Streams[1].Stream)
+	assert.Equal(t, "1", pushes[1].Streams[0].Values[0][1])
+}
+
+func TestHTTPEncoderBatchWait(t *testing.T) {
+	server := newLokiServer(t, 0)
+	defer server.Close()
+
+	enc, err := NewHTTPEncoder(server.URL, server.Client(), types.LogHTTPConfig{
+		BatchWait: 100 * time.Millisecond,
+		Headers:   map[string]string{"X-Scope-OrgID": "tenant"},
+	})
+	assert.NoError(t, err)
+	defer enc.Close()
+	assert.NoError(t, enc.Encode(&types.Log{Name: "nerv", Data: "data"}))
+	time.Sleep(500 * time.Millisecond)
+	assert.Len(t, server.getPushes(), 1)
+}
+
+func TestHTTPEncoderFailure(t *testing.T) {
+	server := newLokiServer(t, 10)
+	defer server.Close()
+
+	enc, err := NewHTTPEncoder(server.URL, server.Client(), types.LogHTTPConfig{
+		BatchSize:  1,
+		MaxRetries: 1,
+		MinBackoff: time.Millisecond,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, enc.Encode(&types.Log{Name: "nerv", Data: "0"}))
+	time.Sleep(100 * time.Millisecond)
+	// the failed delivery is reported by the next encode
+	assert.Error(t, enc.Encode(&types.Log{Name: "nerv", Data: "1"}))
+	assert.NoError(t, enc.Close())
+	// the failed batch is handed back
+	undelivered := enc.Drain()
+	assert.Len(t, undelivered, 1)
+	assert.Equal(t, "0", undelivered[0].Data)
+}
+
+func TestHTTPEncoderQueueFull(t *testing.T) {
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { <-block }))
+	defer server.Close()
+	defer close(block)
+
+	enc, err := NewHTTPEncoder(server.URL, server.Client(), types.LogHTTPConfig{
+		BatchSize: 1,
+		QueueSize: 2,
+	})
+	assert.NoError(t, err)
+	// encode never blocks, even if the remote is stuck
+	full := false
+	for i := 0; i < 10; i++ {
+		if err := enc.Encode(&types.Log{Name: "nerv", Data: strconv.Itoa(i)}); err == common.ErrQueueFull {
+			full = true
+			break
+		}
+	}
+	assert.True(t, full)
+
+	// the batch being sent and the queued ones are drained in order
+	undelivered := enc.Drain()
+	assert.NotEmpty(t, undelivered)
+	for i, logline := range undelivered {
+		assert.Equal(t, strconv.Itoa(i), logline.Data)
+	}
+}
+
+func TestNewWriterWithHTTP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := newLokiServer(t, 0)
+	defer server.Close()
+
+	writer, err := NewWriter(ctx, server.URL+"/loki/api/v1/push", &types.LogConfig{
+		HTTP: types.LogHTTPConfig{BatchSize: 1, Headers: map[string]string{"X-Scope-OrgID": "tenant"}},
+	})
+	assert.NoError(t, err)
+	assert.IsType(t, &sharedEncoder{}, writer.enc)
+	assert.NoError(t, writer.Write(&types.Log{Name: "nerv", Data: "data"}))
+	assert.NoError(t, writer.close())
+	assert.Len(t, server.getPushes(), 1)
+}
+
+func TestWritersShareHTTPEncoder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := newLokiServer(t, 0)
+	defer server.Close()
+
+	config := &types.LogConfig{HTTP: types.LogHTTPConfig{BatchSize: 2, BatchWait: time.Hour, Headers: map[string]string{"X-Scope-OrgID": "tenant"}}}
+	rei, err := NewWriter(ctx, server.URL, config)
+	assert.NoError(t, err)
+	shinji, err := NewWriter(ctx, server.URL, config)
+	assert.NoError(t, err)
+	assert.Same(t, rei.enc, shinji.enc)
+
+	// logs of workloads are sent in the same batch
+	assert.NoError(t, rei.Write(&types.Log{ID: "rei", Name: "nerv", Data: "0"}))
+	assert.NoError(t, shinji.Write(&types.Log{ID: "shinji", Name: "nerv", Data: "1"}))
+	assert.Eventually(t, func() bool { return len(server.getPushes()) == 1 }, time.Second, 10*time.Millisecond)
+
+	// the encoder is closed with the last writer
+	assert.NoError(t, rei.close())
+	assert.NoError(t, shinji.Write(&types.Log{ID: "shinji", Name: "nerv", Data: "2"}))
+	assert.NoError(t, shinji.close())
+	assert.Len(t, server.getPushes(), 2)
+	encodersLock.Lock()
+	defer encodersLock.Unlock()
+	assert.NotContains(t, encoders, server.URL)
+}
+
+func TestWriterWithHTTPSpool(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := newLokiServer(t, 1)
+	defer server.Close()
+
+	writer, err := NewWriter(ctx, server.URL, &types.LogConfig{
+		HTTP:  types.LogHTTPConfig{BatchSize: 1, Headers: map[string]string{"X-Scope-OrgID": "tenant"}},
+		Spool: types.LogSpoolConfig{Dir: t.TempDir()},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Write(&types.Log{Name: "nerv", Data: "0"}))
+	time.Sleep(100 * time.Millisecond)
+	// the failed line is spooled before the new ones
+	assert.Error(t, writer.Write(&types.Log{Name: "nerv", Data: "1"}))
+	assert.Nil(t, writer.enc)
+	assert.NoError(t, writer.Write(&types.Log{Name: "nerv", Data: "2"}))
+
+	writer.reconnect(ctx)
+	assert.NotNil(t, writer.enc)
+	var data []string
+	for _, push := range server.getPushes() {
+		for _, stream := range push.Streams {
+			for _, value := range stream.Values {
+				data = append(data, value[1])
+			}
+		}
+	}
+	assert.Equal(t, []string{"0", "1", "2"}, data)
+	assert.NoError(t, writer.close())
+}
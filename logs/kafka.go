@@ -0,0 +1,185 @@
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/projecteru2/agent/types"
+
+	"github.com/pkg/errors"
+	"github.com/projecteru2/core/log"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	kafkaClientID = "eru-agent"
+	// kafkaBatchTimeout is how long the producer waits for a partition batch to fill,
+	// the logs are already batched by the encoder, so it's short
+	kafkaBatchTimeout = 10 * time.Millisecond
+
+	defaultKafkaBatchSize  = 1000
+	defaultKafkaBatchBytes = 1024 * 1024
+	defaultKafkaBatchWait  = time.Second
+	defaultKafkaQueueSize  = 10000
+	defaultKafkaTimeout    = 10 * time.Second
+)
+
+var (
+	kafkaAcks = map[string]kafka.RequiredAcks{
+		"":       kafka.RequireOne,
+		"none":   kafka.RequireNone,
+		"leader": kafka.RequireOne,
+		"all":    kafka.RequireAll,
+		"0":      kafka.RequireNone,
+		"1":      kafka.RequireOne,
+		"-1":     kafka.RequireAll,
+	}
+	// kafkaTransport is shared by all kafka encoders, so the connections to brokers are shared by workloads,
+	// the metadata is refreshed periodically, so a new partition leader is picked up
+	kafkaTransport = &kafka.Transport{ClientID: kafkaClientID}
+)
+
+// kafkaProducer writes messages to a topic, it's *kafka.Writer
+type kafkaProducer interface {
+	WriteMessages(ctx context.Context, messages ...kafka.Message) error
+	Close() error
+}
+
+// KafkaEncoder publishes logs to a kafka topic in batches, keyed by workload ID,
+// so logs of a workload go to the same partition and stay ordered.
+// Logs are queued in a bounded queue, a failed delivery is returned by the next Encode,
+// the logs not delivered can be drained, so they are spooled instead of lost.
+type KafkaEncoder struct {
+	*batcher
+	topic     string
+	producer  kafkaProducer
+	closeOnce sync.Once
+}
+
+// NewKafkaEncoder checks the topic by fetching its metadata from brokers
+func NewKafkaEncoder(brokers []string, topic string, config types.LogKafkaConfig) (*KafkaEncoder, error) {
+	acks, ok := kafkaAcks[config.Acks]
+	if !ok {
+		return nil, errors.Errorf("invalid acks %s", config.Acks)
+	}
+	if topic == "" {
+		return nil, errors.New("topic is required")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultKafkaTimeout
+	}
+
+	addr := kafka.TCP(brokers...)
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+	client := &kafka.Client{Addr: addr, Transport: kafkaTransport, Timeout: config.Timeout}
+	resp, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range resp.Topics {
+		if t.Error != nil {
+			return nil, t.Error
+		}
+	}
+
+	producer := &kafka.Writer{
+		Addr:         addr,
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		BatchSize:    config.BatchSize,
+		BatchBytes:   int64(config.BatchBytes),
+		BatchTimeout: kafkaBatchTimeout,
+		ReadTimeout:  config.Timeout,
+		WriteTimeout: config.Timeout,
+		RequiredAcks: acks,
+		Transport:    kafkaTransport,
+	}
+	return newKafkaEncoder(topic, producer, config)
+}
+
+func newKafkaEncoder(topic string, producer kafkaProducer, config types.LogKafkaConfig) (*KafkaEncoder, error) {
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultKafkaBatchSize
+	}
+	if config.BatchBytes <= 0 {
+		config.BatchBytes = defaultKafkaBatchBytes
+	}
+	if config.BatchWait <= 0 {
+		config.BatchWait = defaultKafkaBatchWait
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultKafkaQueueSize
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultKafkaTimeout
+	}
+
+	e := &KafkaEncoder{
+		topic:    topic,
+		producer: producer,
+	}
+	var err error
+	if e.batcher, err = newBatcher(config.BatchSize, config.BatchBytes, config.BatchWait, config.QueueSize, config.Timeout, e.send); err != nil {
+		_ = producer.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// Close sends the pending logs and closes the producer
+func (e *KafkaEncoder) Close() error {
+	_ = e.batcher.Close()
+	return e.closeProducer()
+}
+
+// Drain stops sending without flushing the pending logs, returns the logs not delivered in order
+func (e *KafkaEncoder) Drain() []*types.Log {
+	loglines := e.batcher.Drain()
+	_ = e.closeProducer()
+	return loglines
+}
+
+func (e *KafkaEncoder) closeProducer() error {
+	var err error
+	e.closeOnce.Do(func() { err = e.producer.Close() })
+	return err
+}
+
+// send publishes the batch, returns the logs failed to publish,
+// the producer retries and follows the partition leaders by itself
+func (e *KafkaEncoder) send(ctx context.Context, batch []*types.Log) ([]*types.Log, error) {
+	logger := log.WithFunc("send").WithField("topic", e.topic)
+	messages := make([]kafka.Message, 0, len(batch))
+	loglines := make([]*types.Log, 0, len(batch))
+	for _, logline := range batch {
+		value, err := json.Marshal(logline)
+		if err != nil {
+			logger.Error(nil, err, "failed to marshal log") //nolint
+			continue
+		}
+		messages = append(messages, kafka.Message{Key: []byte(logline.ID), Value: value})
+		loglines = append(loglines, logline)
+	}
+
+	err := e.producer.WriteMessages(ctx, messages...)
+	if err == nil {
+		return nil, nil
+	}
+	var writeErrors kafka.WriteErrors
+	if !errors.As(err, &writeErrors) || len(writeErrors) != len(loglines) {
+		logger.Errorf(nil, err, "failed to publish %d lines", len(loglines)) //nolint
+		return loglines, err
+	}
+	// the messages published are not sent again
+	var failed []*types.Log
+	for i, e := range writeErrors {
+		if e != nil {
+			failed = append(failed, loglines[i])
+		}
+	}
+	logger.Errorf(nil, err, "failed to publish %d of %d lines", len(failed), len(loglines)) //nolint
+	return failed, err
+}
@@ -0,0 +1,205 @@
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/types"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// kafkaProducerMock records the messages published,
+// write decides the result of every message, nil means published
+type kafkaProducerMock struct {
+	sync.Mutex
+	messages []kafka.Message
+	write    func(ctx context.Context, message kafka.Message) error
+	closed   bool
+}
+
+func (p *kafkaProducerMock) WriteMessages(ctx context.Context, messages ...kafka.Message) error {
+	p.Lock()
+	defer p.Unlock()
+	errs := make(kafka.WriteErrors, len(messages))
+	failed := false
+	for i, message := range messages {
+		if p.write != nil {
+			errs[i] = p.write(ctx, message)
+		}
+		if errs[i] != nil {
+			failed = true
+			continue
+		}
+		p.messages = append(p.messages, message)
+	}
+	if failed {
+		return errs
+	}
+	return nil
+}
+
+func (p *kafkaProducerMock) Close() error {
+	p.Lock()
+	defer p.Unlock()
+	p.closed = true
+	return nil
+}
+
+func (p *kafkaProducerMock) getMessages(t *testing.T) (keys []string, loglines []*types.Log) {
+	p.Lock()
+	defer p.Unlock()
+	for _, message := range p.messages {
+		logline := &types.Log{}
+		assert.NoError(t, json.Unmarshal(message.Value, logline))
+		keys = append(keys, string(message.Key))
+		loglines = append(loglines, logline)
+	}
+	return keys, loglines
+}
+
+func TestKafkaEncoder(t *testing.T) {
+	_, err := NewKafkaEncoder([]string{"127.0.0.1:1"}, "eru", types.LogKafkaConfig{Acks: "any"})
+	assert.Error(t, err)
+	_, err = NewKafkaEncoder([]string{"127.0.0.1:1"}, "", types.LogKafkaConfig{})
+	assert.Error(t, err)
+	_, err = NewKafkaEncoder([]string{"127.0.0.1:1"}, "eru", types.LogKafkaConfig{Timeout: time.Second})
+	assert.Error(t, err)
+
+	producer := &kafkaProducerMock{}
+	enc, err := newKafkaEncoder("eru", producer, types.LogKafkaConfig{
+		BatchSize: 3,
+		BatchWait: time.Hour,
+	})
+	assert.NoError(t, err)
+	rei := &types.Log{ID: "rei", Name: "nerv", Data: "0"}
+	shinji := &types.Log{ID: "shinji", Name: "nerv", Data: "1"}
+	asuka := &types.Log{ID: "asuka", Name: "nerv", Data: "2"}
+	for _, logline := range []*types.Log{rei, shinji, asuka, rei, shinji} {
+		assert.NoError(t, enc.Encode(logline))
+	}
+	assert.NoError(t, enc.Close())
+	assert.True(t, producer.closed)
+
+	// logs are keyed by workload ID in order
+	keys, loglines := producer.getMessages(t)
+	assert.Equal(t, []string{"rei", "shinji", "asuka", "rei", "shinji"}, keys)
+	for i, logline := range loglines {
+		assert.Equal(t, keys[i], logline.ID)
+	}
+}
+
+func TestKafkaEncoderQueueFull(t *testing.T) {
+	// the producer is stuck until it's drained
+	producer := &kafkaProducerMock{write: func(ctx context.Context, _ kafka.Message) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	enc, err := newKafkaEncoder("eru", producer, types.LogKafkaConfig{
+		BatchSize: 1,
+		QueueSize: 2,
+	})
+	assert.NoError(t, err)
+	// encode never blocks, even if the brokers are stuck
+	full := false
+	for i := 0; i < 10; i++ {
+		if err := enc.Encode(&types.Log{ID: "rei", Data: strconv.Itoa(i)}); err == common.ErrQueueFull {
+			full = true
+			break
+		}
+	}
+	assert.True(t, full)
+
+	// the batch being sent and the queued ones are drained in order
+	undelivered := enc.Drain()
+	assert.NotEmpty(t, undelivered)
+	for i, logline := range undelivered {
+		assert.Equal(t, strconv.Itoa(i), logline.Data)
+	}
+	assert.True(t, producer.closed)
+}
+
+func TestKafkaEncoderPartialFailure(t *testing.T) {
+	// the partition of shinji is unavailable
+	producer := &kafkaProducerMock{write: func(_ context.Context, message kafka.Message) error {
+		if string(message.Key) == "shinji" {
+			return errors.New("leader not available")
+		}
+		return nil
+	}}
+	enc, err := newKafkaEncoder("eru", producer, types.LogKafkaConfig{
+		BatchSize: 4,
+		BatchWait: time.Hour,
+	})
+	assert.NoError(t, err)
+	for i, ID := range []string{"rei", "shinji", "asuka", "shinji", "rei"} {
+		assert.NoError(t, enc.Encode(&types.Log{ID: ID, Data: strconv.Itoa(i)}))
+	}
+	assert.NoError(t, enc.Close())
+
+	// the published logs are not drained again, the failed ones and the ones after are kept in order
+	keys, _ := producer.getMessages(t)
+	assert.Equal(t, []string{"rei", "asuka"}, keys)
+	var data []string
+	for _, logline := range enc.Drain() {
+		data = append(data, logline.Data)
+	}
+	assert.Equal(t, []string{"1", "3", "4"}, data)
+	assert.Error(t, enc.Encode(&types.Log{ID: "rei"}))
+}
+
+func TestNewWriterWithKafka(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// brokers are unreachable, the writer reconnects later
+	writer, err := NewWriter(ctx, "kafka://127.0.0.1:1/eru", &types.LogConfig{
+		Kafka: types.LogKafkaConfig{Timeout: time.Second},
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, writer.enc)
+	assert.True(t, writer.needReconnect)
+	assert.NoError(t, writer.close())
+}
+
+func TestWritersShareKafkaEncoder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr := "kafka://127.0.0.1:9092/eru"
+	producer := &kafkaProducerMock{}
+	enc, err := getEncoder(addr, func() (asyncEncoder, error) {
+		return newKafkaEncoder("eru", producer, types.LogKafkaConfig{BatchSize: 2, BatchWait: time.Hour})
+	})
+	assert.NoError(t, err)
+
+	// writers of the forward use the same producer, no more metadata is fetched
+	rei, err := NewWriter(ctx, addr, &types.LogConfig{})
+	assert.NoError(t, err)
+	shinji, err := NewWriter(ctx, addr, &types.LogConfig{})
+	assert.NoError(t, err)
+	assert.Same(t, enc, rei.enc)
+	assert.Same(t, enc, shinji.enc)
+	assert.NoError(t, rei.Write(&types.Log{ID: "rei", Data: "0"}))
+	assert.NoError(t, shinji.Write(&types.Log{ID: "shinji", Data: "1"}))
+	assert.NoError(t, rei.close())
+	assert.NoError(t, shinji.close())
+	assert.Eventually(t, func() bool {
+		keys, _ := producer.getMessages(t)
+		return assert.ObjectsAreEqual([]string{"rei", "shinji"}, keys)
+	}, time.Second, 10*time.Millisecond)
+
+	// the producer is closed with the last reference
+	producer.Lock()
+	assert.False(t, producer.closed)
+	producer.Unlock()
+	assert.NoError(t, enc.Close())
+	assert.True(t, producer.closed)
+}
@@ -0,0 +1,132 @@
+package logs
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/types"
+	"github.com/projecteru2/agent/utils"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// RateLimitDrop drops all lines exceeding the limit
+	RateLimitDrop = "drop"
+	// RateLimitSample keeps one of every SampleRate lines exceeding the limit
+	RateLimitSample = "sample"
+
+	defaultRateLimitSampleRate     = 100
+	defaultRateLimitReportInterval = 10 * time.Second
+)
+
+// Limiter limits logs of a workload by token bucket,
+// the number of suppressed lines is reported by a synthetic log periodically.
+type Limiter struct {
+	sync.Mutex
+	limiter *rate.Limiter
+	config  types.LogRateLimitConfig
+	emit    func(*types.Log)
+
+	exceeded int
+	dropped  int
+	last     *types.Log
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewLimiter returns nil if rate is 0, emit is used to send the reports
+func NewLimiter(config types.LogRateLimitConfig, emit func(*types.Log)) (*Limiter, error) {
+	if config.Rate < 0 || config.Burst < 0 {
+		return nil, common.ErrInvalidRateLimit
+	}
+	if config.Rate == 0 {
+		return nil, nil
+	}
+	switch config.Mode {
+	case "":
+		config.Mode = RateLimitDrop
+	case RateLimitDrop, RateLimitSample:
+	default:
+		return nil, common.ErrInvalidRateLimit
+	}
+	if config.Burst == 0 {
+		config.Burst = int(config.Rate) + 1
+	}
+	if config.SampleRate <= 0 {
+		config.SampleRate = defaultRateLimitSampleRate
+	}
+	if config.ReportInterval <= 0 {
+		config.ReportInterval = defaultRateLimitReportInterval
+	}
+
+	l := &Limiter{
+		limiter: rate.NewLimiter(rate.Limit(config.Rate), config.Burst),
+		config:  config,
+		emit:    emit,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := utils.Pool.Submit(l.run); err != nil {
+		// nothing would close done, so Close would block forever
+		return nil, err
+	}
+	return l, nil
+}
+
+// Allow returns whether the log should be sent
+func (l *Limiter) Allow(logline *types.Log) bool {
+	if l.limiter.Allow() {
+		return true
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	l.exceeded++
+	if l.config.Mode == RateLimitSample && (l.exceeded-1)%l.config.SampleRate == 0 {
+		sampledLines.WithLabelValues(logline.Name).Inc()
+		return true
+	}
+	l.dropped++
+	l.last = logline
+	rateLimitedLines.WithLabelValues(logline.Name).Inc()
+	return false
+}
+
+// Close stops reporting, the last report is sent if needed
+func (l *Limiter) Close() {
+	close(l.stop)
+	<-l.done
+}
+
+func (l *Limiter) run() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.config.ReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.report()
+		case <-l.stop:
+			l.report()
+			return
+		}
+	}
+}
+
+func (l *Limiter) report() {
+	l.Lock()
+	dropped, last := l.dropped, l.last
+	l.dropped, l.last = 0, nil
+	l.Unlock()
+	if dropped == 0 {
+		return
+	}
+
+	report := *last
+	report.Data = fmt.Sprintf("%d lines dropped by eru-agent because of rate limit", dropped)
+	report.Datetime = time.Now().Format(common.DateTimeFormat)
+	l.emit(&report)
+}
@@ -0,0 +1,69 @@
+package logs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/types"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLimiter(t *testing.T) {
+	l, err := NewLimiter(types.LogRateLimitConfig{}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, l)
+	_, err = NewLimiter(types.LogRateLimitConfig{Rate: -1}, nil)
+	assert.Equal(t, common.ErrInvalidRateLimit, err)
+	_, err = NewLimiter(types.LogRateLimitConfig{Rate: 1, Mode: "block"}, nil)
+	assert.Equal(t, common.ErrInvalidRateLimit, err)
+}
+
+func TestLimiterDrop(t *testing.T) {
+	c := &collector{}
+	l, err := NewLimiter(types.LogRateLimitConfig{Rate: 0.001, Burst: 2, ReportInterval: time.Hour}, c.emit)
+	assert.NoError(t, err)
+
+	before := testutil.ToFloat64(rateLimitedLines.WithLabelValues("drop"))
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if l.Allow(&types.Log{ID: "rei", Name: "drop", Data: "data"}) {
+			allowed++
+		}
+	}
+	assert.Equal(t, 2, allowed)
+	assert.Equal(t, float64(8), testutil.ToFloat64(rateLimitedLines.WithLabelValues("drop"))-before)
+
+	// the report is sent on closing
+	l.Close()
+	assert.Equal(t, []string{"8 lines dropped by eru-agent because of rate limit"}, c.get())
+}
+
+func TestLimiterSample(t *testing.T) {
+	c := &collector{}
+	l, err := NewLimiter(types.LogRateLimitConfig{
+		Rate:           0.001,
+		Burst:          1,
+		Mode:           RateLimitSample,
+		SampleRate:     3,
+		ReportInterval: 100 * time.Millisecond,
+	}, c.emit)
+	assert.NoError(t, err)
+	defer l.Close()
+
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if l.Allow(&types.Log{ID: "rei", Name: "sample", Data: "data"}) {
+			allowed++
+		}
+	}
+	// 1 by burst, then 3 of 9 by sampling
+	assert.Equal(t, 4, allowed)
+	assert.Equal(t, float64(3), testutil.ToFloat64(sampledLines.WithLabelValues("sample")))
+
+	// reported periodically
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, []string{"6 lines dropped by eru-agent because of rate limit"}, c.get())
+}
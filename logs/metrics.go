@@ -0,0 +1,31 @@
+package logs
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	spoolBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "log_spool_bytes",
+		Help: "bytes of logs spooled on disk, waiting to be forwarded.",
+	}, []string{"forward"})
+	spoolDroppedLines = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "log_spool_dropped_lines_total",
+		Help: "lines dropped by log spool because of size or age limit.",
+	}, []string{"forward"})
+	rateLimitedLines = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "log_rate_limited_lines_total",
+		Help: "lines dropped by log rate limit.",
+	}, []string{"app"})
+	sampledLines = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "log_sampled_lines_total",
+		Help: "lines kept by sampling while exceeding log rate limit.",
+	}, []string{"app"})
+)
+
+func init() { //nolint:gochecknoinits
+	prometheus.MustRegister(
+		spoolBytes,
+		spoolDroppedLines,
+		rateLimitedLines,
+		sampledLines,
+	)
+}
@@ -0,0 +1,112 @@
+package logs
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projecteru2/agent/types"
+)
+
+const (
+	defaultMultilineMaxLines     = 500
+	defaultMultilineMaxBytes     = 64 * 1024
+	defaultMultilineFlushTimeout = time.Second
+)
+
+// Merger merges multi-line events like stack traces into one log.
+// A log is emitted when the next event starts, the limits are reached,
+// or no more line comes in flush timeout.
+type Merger struct {
+	sync.Mutex
+	config       types.LogMultilineConfig
+	continuation []*regexp.Regexp
+	emit         func(*types.Log)
+
+	pending *types.Log
+	lines   int
+	timer   *time.Timer
+}
+
+// NewMerger .
+func NewMerger(config types.LogMultilineConfig, emit func(*types.Log)) (*Merger, error) {
+	if config.MaxLines <= 0 {
+		config.MaxLines = defaultMultilineMaxLines
+	}
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = defaultMultilineMaxBytes
+	}
+	if config.FlushTimeout <= 0 {
+		config.FlushTimeout = defaultMultilineFlushTimeout
+	}
+	m := &Merger{config: config, emit: emit}
+	for _, pattern := range config.Continuation {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		m.continuation = append(m.continuation, re)
+	}
+	return m, nil
+}
+
+// Add merges the log into the pending event if it's a continuation, or starts a new event
+func (m *Merger) Add(logline *types.Log) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.pending != nil && m.isContinuation(logline.Data) &&
+		m.lines < m.config.MaxLines && len(m.pending.Data)+1+len(logline.Data) <= m.config.MaxBytes {
+		m.pending.Data += "\n" + logline.Data
+		m.lines++
+	} else {
+		m.flush()
+		m.pending = logline
+		m.lines = 1
+	}
+
+	if m.timer == nil {
+		m.timer = time.AfterFunc(m.config.FlushTimeout, m.Flush)
+	} else {
+		m.timer.Reset(m.config.FlushTimeout)
+	}
+}
+
+// Flush emits the pending event
+func (m *Merger) Flush() {
+	m.Lock()
+	defer m.Unlock()
+	m.flush()
+}
+
+// Close stops the timer and emits the pending event
+func (m *Merger) Close() {
+	m.Lock()
+	defer m.Unlock()
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.flush()
+}
+
+func (m *Merger) flush() {
+	if m.pending == nil {
+		return
+	}
+	m.emit(m.pending)
+	m.pending = nil
+	m.lines = 0
+}
+
+func (m *Merger) isContinuation(data string) bool {
+	if m.config.Indent && (strings.HasPrefix(data, " ") || strings.HasPrefix(data, "\t")) {
+		return true
+	}
+	for _, re := range m.continuation {
+		if re.MatchString(data) {
+			return true
+		}
+	}
+	return false
+}
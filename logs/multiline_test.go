@@ -0,0 +1,80 @@
+package logs
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/projecteru2/agent/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type collector struct {
+	sync.Mutex
+	data []string
+}
+
+func (c *collector) emit(logline *types.Log) {
+	c.Lock()
+	defer c.Unlock()
+	c.data = append(c.data, logline.Data)
+}
+
+func (c *collector) get() []string {
+	c.Lock()
+	defer c.Unlock()
+	return c.data
+}
+
+func TestMerger(t *testing.T) {
+	_, err := NewMerger(types.LogMultilineConfig{Continuation: []string{"("}}, nil)
+	assert.Error(t, err)
+
+	c := &collector{}
+	m, err := NewMerger(types.LogMultilineConfig{
+		Continuation: []string{`^Caused by:`},
+		Indent:       true,
+		FlushTimeout: time.Hour,
+	}, c.emit)
+	assert.NoError(t, err)
+	for _, data := range []string{
+		"  orphan",
+		"Exception in thread \"main\" java.lang.RuntimeException: boom",
+		"\tat Nerv.main(Nerv.java:1)",
+		"Caused by: java.lang.NullPointerException",
+		"\t... 1 more",
+		"next",
+	} {
+		m.Add(&types.Log{Data: data})
+	}
+	assert.Equal(t, []string{
+		"  orphan",
+		"Exception in thread \"main\" java.lang.RuntimeException: boom\n\tat Nerv.main(Nerv.java:1)\nCaused by: java.lang.NullPointerException\n\t... 1 more",
+	}, c.get())
+	m.Close()
+	assert.Equal(t, "next", c.get()[2])
+}
+
+func TestMergerLimits(t *testing.T) {
+	c := &collector{}
+	m, err := NewMerger(types.LogMultilineConfig{Indent: true, MaxLines: 2, MaxBytes: 10, FlushTimeout: time.Hour}, c.emit)
+	assert.NoError(t, err)
+	for _, data := range []string{"a", " b", " c", "d", " 123456789"} {
+		m.Add(&types.Log{Data: data})
+	}
+	m.Close()
+	assert.Equal(t, []string{"a\n b", " c", "d", " 123456789"}, c.get())
+}
+
+func TestMergerFlushTimeout(t *testing.T) {
+	c := &collector{}
+	m, err := NewMerger(types.LogMultilineConfig{Indent: true, FlushTimeout: 100 * time.Millisecond}, c.emit)
+	assert.NoError(t, err)
+	defer m.Close()
+	m.Add(&types.Log{Data: "panic: boom"})
+	m.Add(&types.Log{Data: "\tmain.go:1"})
+	assert.Empty(t, c.get())
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, []string{"panic: boom\n\tmain.go:1"}, c.get())
+}
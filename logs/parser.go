@@ -0,0 +1,120 @@
+package logs
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/types"
+
+	"github.com/go-logfmt/logfmt"
+)
+
+const (
+	// ParserJSON parses JSON objects
+	ParserJSON = "json"
+	// ParserLogfmt parses logfmt lines
+	ParserLogfmt = "logfmt"
+	// ParserAuto tries JSON first, then logfmt
+	ParserAuto = "auto"
+)
+
+var defaultPromote = []string{"level", "trace_id", "msg"}
+
+// Parser parses structured logs and promotes the selected keys into Extra,
+// Data is always kept as it is, lines failed to parse are not changed.
+type Parser struct {
+	format  string
+	promote []string
+}
+
+// NewParser .
+func NewParser(format string, promote []string) (*Parser, error) {
+	switch format {
+	case ParserJSON, ParserLogfmt, ParserAuto:
+	default:
+		return nil, common.ErrInvalidParser
+	}
+	if len(promote) == 0 {
+		promote = defaultPromote
+	}
+	return &Parser{format: format, promote: promote}, nil
+}
+
+// Parse returns the log with promoted fields, the Extra of original log is not modified
+func (p *Parser) Parse(logline *types.Log) *types.Log {
+	var fields map[string]string
+	switch p.format {
+	case ParserJSON:
+		fields = parseJSON(logline.Data)
+	case ParserLogfmt:
+		fields = parseLogfmt(logline.Data)
+	case ParserAuto:
+		if fields = parseJSON(logline.Data); fields == nil {
+			fields = parseLogfmt(logline.Data)
+		}
+	}
+	if fields == nil {
+		return logline
+	}
+
+	extra := make(map[string]string, len(logline.Extra)+len(p.promote))
+	for key, value := range logline.Extra {
+		extra[key] = value
+	}
+	promoted := false
+	for _, key := range p.promote {
+		if value, ok := fields[key]; ok {
+			extra[key] = value
+			promoted = true
+		}
+	}
+	if !promoted {
+		return logline
+	}
+
+	l := *logline
+	l.Extra = extra
+	return &l
+}
+
+// parseJSON returns nil if data is not a JSON object, values which are not strings are kept in JSON
+func parseJSON(data string) map[string]string {
+	data = strings.TrimSpace(data)
+	if !strings.HasPrefix(data, "{") {
+		return nil
+	}
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(data), &raw); err != nil {
+		return nil
+	}
+	fields := make(map[string]string, len(raw))
+	for key, value := range raw {
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			fields[key] = s
+		} else {
+			fields[key] = string(value)
+		}
+	}
+	return fields
+}
+
+// parseLogfmt returns nil if data is not logfmt, at least one key=value pair is required
+func parseLogfmt(data string) map[string]string {
+	fields := map[string]string{}
+	hasValue := false
+	d := logfmt.NewDecoder(strings.NewReader(data))
+	for d.ScanRecord() {
+		for d.ScanKeyval() {
+			if d.Value() != nil {
+				hasValue = true
+			}
+			fields[string(d.Key())] = string(d.Value())
+		}
+	}
+	if d.Err() != nil || !hasValue {
+		return nil
+	}
+	return fields
+}
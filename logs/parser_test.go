@@ -0,0 +1,50 @@
+package logs
+
+import (
+	"testing"
+
+	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParser(t *testing.T) {
+	_, err := NewParser("xml", nil)
+	assert.Equal(t, common.ErrInvalidParser, err)
+
+	p, err := NewParser(ParserAuto, nil)
+	assert.NoError(t, err)
+	extra := map[string]string{"nodename": "tokyo3"}
+
+	data := `{"level":"error","msg":"boom","trace_id":"abc","code":500}`
+	l := p.Parse(&types.Log{Data: data, Extra: extra})
+	assert.Equal(t, data, l.Data)
+	assert.Equal(t, map[string]string{"nodename": "tokyo3", "level": "error", "msg": "boom", "trace_id": "abc"}, l.Extra)
+	// the shared extra is not modified
+	assert.Equal(t, map[string]string{"nodename": "tokyo3"}, extra)
+
+	l = p.Parse(&types.Log{Data: `level=warn msg="slow query" took=3s`, Extra: extra})
+	assert.Equal(t, map[string]string{"nodename": "tokyo3", "level": "warn", "msg": "slow query"}, l.Extra)
+
+	// raw lines are kept
+	for _, data := range []string{"plain text", `{"broken`, `{"other":"keys"}`} {
+		raw := &types.Log{Data: data, Extra: extra}
+		assert.Same(t, raw, p.Parse(raw))
+	}
+}
+
+func TestParserFormat(t *testing.T) {
+	p, err := NewParser(ParserJSON, []string{"code"})
+	assert.NoError(t, err)
+	l := p.Parse(&types.Log{Data: `{"level":"error","code":500}`})
+	assert.Equal(t, map[string]string{"code": "500"}, l.Extra)
+	raw := &types.Log{Data: "code=500"}
+	assert.Same(t, raw, p.Parse(raw))
+
+	p, err = NewParser(ParserLogfmt, nil)
+	assert.NoError(t, err)
+	raw = &types.Log{Data: `{"level":"error"}`}
+	assert.Same(t, raw, p.Parse(raw))
+	assert.Equal(t, "info", p.Parse(&types.Log{Data: "level=info"}).Extra["level"])
+}
@@ -0,0 +1,195 @@
+package logs
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projecteru2/agent/types"
+
+	"github.com/pkg/errors"
+	"github.com/projecteru2/core/log"
+	"gopkg.in/yaml.v2"
+)
+
+const defaultRedactionReloadInterval = 10 * time.Second
+
+// builtinRedactionRules are common shapes of credentials
+var builtinRedactionRules = []types.LogRedactionRule{
+	{Name: "private_key", Pattern: `(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`, Replacement: "[REDACTED]"},
+	{Name: "aws_access_key", Pattern: `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`, Replacement: "[REDACTED]"},
+	{Name: "github_token", Pattern: `\bgh[pousr]_[A-Za-z0-9]{36,}\b`, Replacement: "[REDACTED]"},
+	{Name: "jwt", Pattern: `\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`, Replacement: "[REDACTED]"},
+	{Name: "bearer", Pattern: `(?i)(bearer\s+)[A-Za-z0-9._~+/-]+=*`, Replacement: "${1}[REDACTED]"},
+	{Name: "url_password", Pattern: `(://[^:/\s@]+:)[^@/\s]+@`, Replacement: "${1}[REDACTED]@"},
+	{Name: "password", Pattern: `(?i)((?:password|passwd|pwd|secret|token|api_?key|access_?key)["']?\s*[:=]\s*["']?)[^\s"',;&]+`, Replacement: "${1}[REDACTED]"},
+}
+
+type redactionRule struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+type redactionRules struct {
+	Rules []types.LogRedactionRule `yaml:"rules"`
+}
+
+// Redactor replaces secrets in logs by the builtin rules and the rules file,
+// the rules file is reloaded when it's modified.
+type Redactor struct {
+	sync.RWMutex
+	config  types.LogRedactionConfig
+	rules   []*redactionRule
+	modTime time.Time
+}
+
+// NewRedactor returns nil if there is no rule
+func NewRedactor(config types.LogRedactionConfig) (*Redactor, error) {
+	if !config.Builtin && config.Rules == "" {
+		return nil, nil
+	}
+	if config.ReloadInterval <= 0 {
+		config.ReloadInterval = defaultRedactionReloadInterval
+	}
+	r := &Redactor{config: config}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Redact returns the log with secrets in data and extra fields replaced, the original log is not modified
+func (r *Redactor) Redact(logline *types.Log) *types.Log {
+	r.RLock()
+	defer r.RUnlock()
+	data := r.replace(logline.Data)
+	var extra map[string]string
+	for key, value := range logline.Extra {
+		// the key is redacted with the value, so rules like password=... match the fields
+		field := r.replace(key + "=" + value)
+		if field == key+"="+value {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]string, len(logline.Extra))
+			for k, v := range logline.Extra {
+				extra[k] = v
+			}
+		}
+		if redacted, ok := strings.CutPrefix(field, key+"="); ok {
+			extra[key] = redacted
+		} else {
+			extra[key] = "[REDACTED]"
+		}
+	}
+	if data == logline.Data && extra == nil {
+		return logline
+	}
+	l := *logline
+	l.Data = data
+	if extra != nil {
+		l.Extra = extra
+	}
+	return &l
+}
+
+func (r *Redactor) replace(s string) string {
+	for _, rule := range r.rules {
+		s = rule.re.ReplaceAllString(s, rule.replacement)
+	}
+	return s
+}
+
+// Reload loads the rules, the rules are not changed on failure.
+// Rules in the file override the builtin rules with the same name.
+func (r *Redactor) Reload() error {
+	var rules []types.LogRedactionRule
+	if r.config.Builtin {
+		rules = append(rules, builtinRedactionRules...)
+	}
+
+	var modTime time.Time
+	if r.config.Rules != "" {
+		info, err := os.Stat(r.config.Rules)
+		if err != nil {
+			return err
+		}
+		modTime = info.ModTime()
+		content, err := os.ReadFile(r.config.Rules)
+		if err != nil {
+			return err
+		}
+		file := &redactionRules{}
+		if err := yaml.Unmarshal(content, file); err != nil {
+			return err
+		}
+		for _, rule := range file.Rules {
+			rules = overrideRedactionRule(rules, rule)
+		}
+	}
+
+	compiled := make([]*redactionRule, 0, len(rules))
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return errors.Wrapf(err, "invalid redaction rule %s", rule.Name)
+		}
+		compiled = append(compiled, &redactionRule{re: re, replacement: rule.Replacement})
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.rules = compiled
+	r.modTime = modTime
+	return nil
+}
+
+// Watch reloads the rules file when it's modified, blocks until ctx is done
+func (r *Redactor) Watch(ctx context.Context) {
+	if r.config.Rules == "" {
+		return
+	}
+	logger := log.WithFunc("Watch").WithField("rules", r.config.Rules)
+	ticker := time.NewTicker(r.config.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(r.config.Rules)
+			if err != nil {
+				logger.Error(ctx, err, "failed to stat redaction rules")
+				continue
+			}
+			r.RLock()
+			modified := !info.ModTime().Equal(r.modTime)
+			r.RUnlock()
+			if !modified {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				logger.Error(ctx, err, "failed to reload redaction rules, keep the old ones")
+				// don't retry until it's modified again
+				r.Lock()
+				r.modTime = info.ModTime()
+				r.Unlock()
+				continue
+			}
+			logger.Info(ctx, "redaction rules reloaded")
+		}
+	}
+}
+
+func overrideRedactionRule(rules []types.LogRedactionRule, rule types.LogRedactionRule) []types.LogRedactionRule {
+	for i := range rules {
+		if rules[i].Name == rule.Name && rule.Name != "" {
+			rules[i] = rule
+			return rules
+		}
+	}
+	return append(rules, rule)
+}
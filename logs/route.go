@@ -0,0 +1,88 @@
+package logs
+
+import (
+	"path"
+
+	"github.com/projecteru2/agent/types"
+	"github.com/projecteru2/agent/utils"
+)
+
+// Router picks forwards for logs by the routing table.
+// Logs go to the forwards of every matching route,
+// if no route matches, they go to the default forwards,
+// if there are no default forwards, one of the forwards is picked by hashing the workload ID.
+type Router struct {
+	routes   []types.LogRouteConfig
+	defaults []string
+	forwards *utils.HashBackends
+}
+
+// NewRouter returns a router, patterns in routes are validated
+func NewRouter(config *types.LogConfig) (*Router, error) {
+	for _, route := range config.Routes {
+		for _, pattern := range []string{route.Name, route.EntryPoint} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &Router{
+		routes:   config.Routes,
+		defaults: config.Default,
+		forwards: utils.NewHashBackends(config.Forwards),
+	}, nil
+}
+
+// Get returns the forwards of the log, only Name, EntryPoint and Extra are used for matching,
+// labels are the labels of the workload
+func (r *Router) Get(ID string, logline *types.Log, labels map[string]string) []string {
+	forwards := []string{}
+	seen := map[string]struct{}{}
+	for _, route := range r.routes {
+		if !match(route, logline, labels) {
+			continue
+		}
+		for _, forward := range route.Forwards {
+			if _, ok := seen[forward]; ok {
+				continue
+			}
+			seen[forward] = struct{}{}
+			forwards = append(forwards, forward)
+		}
+	}
+
+	switch {
+	case len(seen) > 0:
+		return forwards
+	case len(r.defaults) > 0:
+		return r.defaults
+	}
+	if forward := r.forwards.Get(ID, 0); forward != "" {
+		return []string{forward}
+	}
+	return forwards
+}
+
+func match(route types.LogRouteConfig, logline *types.Log, labels map[string]string) bool {
+	if route.Name != "" {
+		if ok, _ := path.Match(route.Name, logline.Name); !ok {
+			return false
+		}
+	}
+	if route.EntryPoint != "" {
+		if ok, _ := path.Match(route.EntryPoint, logline.EntryPoint); !ok {
+			return false
+		}
+	}
+	for key, value := range route.Extra {
+		if v, ok := logline.Extra[key]; !ok || v != value {
+			return false
+		}
+	}
+	for key, value := range route.Labels {
+		if v, ok := labels[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
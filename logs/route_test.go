@@ -0,0 +1,51 @@
+package logs
+
+import (
+	"testing"
+
+	"github.com/projecteru2/agent/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter(t *testing.T) {
+	config := &types.LogConfig{
+		Forwards: []string{"tcp://127.0.0.1:5144"},
+		Routes: []types.LogRouteConfig{
+			{Name: "nerv", EntryPoint: "eva*", Forwards: []string{"journal://", "tcp://127.0.0.1:5145"}},
+			{Extra: map[string]string{"podname": "tokyo3"}, Forwards: []string{"tcp://127.0.0.1:5145", "udp://127.0.0.1:5146"}},
+			{Name: "nerv", Labels: map[string]string{"team": "magi"}, Forwards: []string{"http://127.0.0.1:3100/loki/api/v1/push"}},
+		},
+	}
+	router, err := NewRouter(config)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"journal://", "tcp://127.0.0.1:5145"}, router.Get("Rei", &types.Log{Name: "nerv", EntryPoint: "eva0"}, nil))
+	assert.Equal(t, []string{"journal://", "tcp://127.0.0.1:5145", "udp://127.0.0.1:5146"}, router.Get("Rei", &types.Log{
+		Name:       "nerv",
+		EntryPoint: "eva0",
+		Extra:      map[string]string{"podname": "tokyo3"},
+	}, nil))
+
+	// routed by the labels of workload
+	assert.Equal(t, []string{"http://127.0.0.1:3100/loki/api/v1/push"}, router.Get("Ritsuko", &types.Log{Name: "nerv", EntryPoint: "lab"}, map[string]string{"team": "magi"}))
+	assert.Equal(t, []string{"tcp://127.0.0.1:5144"}, router.Get("Ritsuko", &types.Log{Name: "nerv", EntryPoint: "lab"}, map[string]string{"team": "nerv"}))
+
+	// fallback to forwards
+	assert.Equal(t, []string{"tcp://127.0.0.1:5144"}, router.Get("Gendo", &types.Log{Name: "nerv", EntryPoint: "commander"}, nil))
+
+	// fallback to default
+	config.Default = []string{"journal://"}
+	router, err = NewRouter(config)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"journal://"}, router.Get("Gendo", &types.Log{Name: "nerv", EntryPoint: "commander"}, nil))
+
+	// nothing at all
+	router, err = NewRouter(&types.LogConfig{})
+	assert.NoError(t, err)
+	assert.Empty(t, router.Get("Gendo", &types.Log{}, nil))
+
+	// invalid pattern
+	_, err = NewRouter(&types.LogConfig{Routes: []types.LogRouteConfig{{Name: "["}}})
+	assert.Error(t, err)
+}
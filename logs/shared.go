@@ -0,0 +1,126 @@
+package logs
+
+import (
+	"sync"
+
+	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/types"
+
+	"github.com/projecteru2/core/log"
+)
+
+var (
+	encoders     = map[string]*sharedEncoder{}
+	encodersLock sync.Mutex
+)
+
+// sharedEncoder shares an async encoder among the writers of a forward,
+// so the logs of all workloads are sent in the same batches.
+// Every writer holds a reference, which is released by Close or Drain.
+type sharedEncoder struct {
+	sync.RWMutex
+	asyncEncoder
+	forward string
+	refs    int // guarded by encodersLock
+	drained bool
+}
+
+// getEncoder returns the shared encoder of the forward, create is called if there is none
+func getEncoder(forward string, create func() (asyncEncoder, error)) (*sharedEncoder, error) {
+	encodersLock.Lock()
+	defer encodersLock.Unlock()
+
+	if e, ok := encoders[forward]; ok {
+		e.refs++
+		return e, nil
+	}
+	enc, err := create()
+	if err != nil {
+		return nil, err
+	}
+	e := &sharedEncoder{asyncEncoder: enc, forward: forward, refs: 1}
+	encoders[forward] = e
+	return e, nil
+}
+
+// Encode returns ErrEncoderClosed if it's drained by another writer
+func (e *sharedEncoder) Encode(logline *types.Log) error {
+	e.RLock()
+	defer e.RUnlock()
+	if e.drained {
+		return common.ErrEncoderClosed
+	}
+	return e.asyncEncoder.Encode(logline)
+}
+
+// Close releases the reference, the last one sends the pending logs and closes the encoder,
+// the logs failed to send are spooled
+func (e *sharedEncoder) Close() error {
+	if !e.release() {
+		return nil
+	}
+	e.Lock()
+	defer e.Unlock()
+	if e.drained {
+		return nil
+	}
+	e.drained = true
+	err := e.asyncEncoder.Close()
+	spoolLines(findSpool(e.forward), e.forward, e.asyncEncoder.Drain())
+	return err
+}
+
+// Drain stops the encoder for all writers, and releases the reference.
+// The logs not delivered are spooled with lock held, before the writers see ErrEncoderClosed,
+// so they stay ahead of the logs spooled by the writers after, nothing is returned.
+func (e *sharedEncoder) Drain() []*types.Log {
+	e.Lock()
+	if !e.drained {
+		e.drained = true
+		e.unregister()
+		spoolLines(findSpool(e.forward), e.forward, e.asyncEncoder.Drain())
+	}
+	e.Unlock()
+	e.release()
+	return nil
+}
+
+// release drops a reference, returns true if it's the last one
+func (e *sharedEncoder) release() bool {
+	encodersLock.Lock()
+	defer encodersLock.Unlock()
+	if e.refs--; e.refs > 0 {
+		return false
+	}
+	if encoders[e.forward] == e {
+		delete(encoders, e.forward)
+	}
+	return true
+}
+
+// unregister makes the writers reconnecting create a new encoder
+func (e *sharedEncoder) unregister() {
+	encodersLock.Lock()
+	defer encodersLock.Unlock()
+	if encoders[e.forward] == e {
+		delete(encoders, e.forward)
+	}
+}
+
+// spoolLines appends the logs to spool in order, the logs are dropped if there is no spool
+func spoolLines(spool *Spool, forward string, loglines []*types.Log) {
+	if len(loglines) == 0 {
+		return
+	}
+	logger := log.WithFunc("spoolLines")
+	if spool == nil {
+		logger.Warnf(nil, "drop %d lines not delivered to %s", len(loglines), forward) //nolint
+		return
+	}
+	for i, logline := range loglines {
+		if err := spool.Append(logline); err != nil {
+			logger.Warnf(nil, "drop %d lines not delivered to %s: %s", len(loglines)-i, forward, err) //nolint
+			return
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/types"
+
+	"github.com/projecteru2/core/log"
+)
+
+const (
+	segmentSuffix = ".seg"
+
+	defaultSpoolMaxBytes     = 100 * 1024 * 1024
+	defaultSpoolSegmentBytes = 8 * 1024 * 1024
+	defaultSpoolMaxAge       = 24 * time.Hour
+)
+
+var (
+	spools     = map[string]*Spool{}
+	spoolsLock sync.Mutex
+)
+
+type segment struct {
+	seq     uint64
+	path    string
+	size    int64 // bytes written into this segment
+	offset  int64 // bytes already replayed
+	lines   int64 // lines not replayed yet
+	created time.Time
+}
+
+// Spool is a bounded on-disk queue of log lines.
+// Writers append to it while the forward is unreachable,
+// and replay it in order once the forward comes back.
+type Spool struct {
+	sync.Mutex
+	dir          string
+	forward      string
+	maxBytes     int64
+	segmentBytes int64
+	maxAge       time.Duration
+
+	segments []*segment
+	size     int64    // bytes not replayed yet
+	file     *os.File // file of the last segment, nil if it's not writable
+}
+
+// getSpool returns the spool of the forward, all writers of the same forward share one spool
+func getSpool(forward string, config types.LogSpoolConfig) (*Spool, error) {
+	spoolsLock.Lock()
+	defer spoolsLock.Unlock()
+
+	if spool, ok := spools[forward]; ok {
+		return spool, nil
+	}
+	name := strings.NewReplacer(":", "_", "/", "_").Replace(forward)
+	spool, err := NewSpool(filepath.Join(config.Dir, name), forward, config)
+	if err != nil {
+		return nil, err
+	}
+	spools[forward] = spool
+	return spool, nil
+}
+
+// findSpool returns the spool of the forward opened by writers, nil if there is none
+func findSpool(forward string) *Spool {
+	spoolsLock.Lock()
+	defer spoolsLock.Unlock()
+	return spools[forward]
+}
+
+// NewSpool opens the spool in dir, segments left by the last run will be loaded
+func NewSpool(dir string, forward string, config types.LogSpoolConfig) (*Spool, error) {
+	s := &Spool{
+		dir:          dir,
+		forward:      forward,
+		maxBytes:     config.MaxBytes,
+		segmentBytes: config.SegmentBytes,
+		maxAge:       config.MaxAge,
+	}
+	if s.maxBytes <= 0 {
+		s.maxBytes = defaultSpoolMaxBytes
+	}
+	if s.segmentBytes <= 0 {
+		s.segmentBytes = defaultSpoolSegmentBytes
+	}
+	if s.maxAge <= 0 {
+		s.maxAge = defaultSpoolMaxAge
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Spool) load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), segmentSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		seg := &segment{
+			seq:     seq,
+			path:    filepath.Join(s.dir, entry.Name()),
+			size:    info.Size(),
+			created: info.ModTime(),
+		}
+		if seg.lines, err = countLines(seg.path); err != nil {
+			return err
+		}
+		s.segments = append(s.segments, seg)
+		s.size += seg.size
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+	spoolBytes.WithLabelValues(s.forward).Set(float64(s.size))
+	return nil
+}
+
+// Len returns bytes not replayed yet
+func (s *Spool) Len() int64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.size
+}
+
+// Append appends a log line to the spool
+func (s *Spool) Append(logline *types.Log) error {
+	s.Lock()
+	defer s.Unlock()
+	s.expire()
+
+	data, err := json.Marshal(logline)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if s.size+int64(len(data)) > s.maxBytes {
+		spoolDroppedLines.WithLabelValues(s.forward).Inc()
+		return common.ErrSpoolFull
+	}
+
+	seg, err := s.writableSegment()
+	if err != nil {
+		return err
+	}
+	n, err := s.file.Write(data)
+	seg.size += int64(n)
+	s.size += int64(n)
+	spoolBytes.WithLabelValues(s.forward).Set(float64(s.size))
+	if err != nil {
+		return err
+	}
+	seg.lines++
+	return nil
+}
+
+// Replay sends spooled lines to f in order, replayed lines are removed from the spool.
+// If f fails, Replay stops and the failed line will be the first one of the next replay.
+func (s *Spool) Replay(f func(*types.Log) error) error {
+	return s.ReplayBatch(1, func(loglines []*types.Log) error { return f(loglines[0]) })
+}
+
+// ReplayBatch sends spooled lines to f in batches of at most n lines in order,
+// a batch is removed from the spool after f returns, so f should deliver the lines before returning.
+// If f fails, ReplayBatch stops and the failed batch will be replayed again.
+func (s *Spool) ReplayBatch(n int, f func([]*types.Log) error) error {
+	return s.ReplayChunk(n, 0, f)
+}
+
+// ReplayChunk is ReplayBatch, but replays at most limit lines, 0 means no limit.
+// The spool is locked while replaying, so appending waits, replay in chunks to keep the waiting short.
+func (s *Spool) ReplayChunk(n, limit int, f func([]*types.Log) error) error {
+	s.Lock()
+	defer s.Unlock()
+	s.expire()
+
+	for len(s.segments) > 0 {
+		seg := s.segments[0]
+		replayed, eof, err := s.replaySegment(seg, n, limit, f)
+		if err != nil {
+			return err
+		}
+		// the segment is not written any more, remove it once it's replayed to the end
+		if !eof && seg.offset < seg.size {
+			return nil
+		}
+		s.removeFirst()
+		if limit > 0 {
+			if limit -= replayed; limit <= 0 {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// Close closes the writing segment
+func (s *Spool) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	return s.closeFile()
+}
+
+// replaySegment replays at most limit lines of the segment, 0 means no limit,
+// returns the lines replayed, and whether the segment is replayed to the end
+func (s *Spool) replaySegment(seg *segment, n, limit int, f func([]*types.Log) error) (int, bool, error) {
+	if len(s.segments) == 1 {
+		// stop writing to the segment being replayed
+		if err := s.closeFile(); err != nil {
+			return 0, false, err
+		}
+	}
+	file, err := os.Open(seg.path)
+	if err != nil {
+		return 0, false, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(seg.offset, io.SeekStart); err != nil {
+		return 0, false, err
+	}
+
+	replayed := 0
+	var batch []*types.Log
+	var lines, size int64 // lines and bytes read but not replayed yet
+	commit := func() error {
+		if len(batch) > 0 {
+			if err := f(batch); err != nil {
+				return err
+			}
+		}
+		seg.offset += size
+		seg.lines -= lines
+		replayed += int(lines)
+		s.size -= size
+		spoolBytes.WithLabelValues(s.forward).Set(float64(s.size))
+		batch, lines, size = nil, 0, 0
+		return nil
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// an incomplete line left by a crash, nothing we can do
+			err := commit()
+			return replayed, err == nil, err
+		}
+		if err != nil {
+			return replayed, false, err
+		}
+
+		logline := &types.Log{}
+		if err := json.Unmarshal(data, logline); err != nil {
+			log.WithFunc("replaySegment").Warnf(nil, "invalid line in %s: %s", seg.path, err) //nolint
+			spoolDroppedLines.WithLabelValues(s.forward).Inc()
+		} else {
+			batch = append(batch, logline)
+		}
+		lines++
+		size += int64(len(data))
+		if len(batch) >= n || (limit > 0 && replayed+int(lines) >= limit) {
+			if err := commit(); err != nil {
+				return replayed, false, err
+			}
+		}
+		if limit > 0 && replayed >= limit {
+			return replayed, false, nil
+		}
+	}
+}
+
+// writableSegment returns the last segment, creates a new one if it's full or not opened
+func (s *Spool) writableSegment() (*segment, error) {
+	var last *segment
+	var seq uint64
+	if len(s.segments) > 0 {
+		last = s.segments[len(s.segments)-1]
+		seq = last.seq + 1
+	}
+	if s.file != nil && last.size < s.segmentBytes {
+		return last, nil
+	}
+	if err := s.closeFile(); err != nil {
+		return nil, err
+	}
+
+	seg := &segment{
+		seq:     seq,
+		path:    filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, segmentSuffix)),
+		created: time.Now(),
+	}
+	file, err := os.OpenFile(seg.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+	s.segments = append(s.segments, seg)
+	return seg, nil
+}
+
+// expire drops segments older than maxAge
+func (s *Spool) expire() {
+	for len(s.segments) > 0 && time.Since(s.segments[0].created) > s.maxAge {
+		seg := s.segments[0]
+		log.WithFunc("expire").Warnf(nil, "drop %d expired lines of %s", seg.lines, s.forward) //nolint
+		spoolDroppedLines.WithLabelValues(s.forward).Add(float64(seg.lines))
+		s.removeFirst()
+	}
+}
+
+func (s *Spool) removeFirst() {
+	seg := s.segments[0]
+	if len(s.segments) == 1 {
+		_ = s.closeFile()
+	}
+	if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+		log.WithFunc("removeFirst").Errorf(nil, err, "failed to remove segment %s", seg.path) //nolint
+	}
+	s.size -= seg.size - seg.offset
+	s.segments = s.segments[1:]
+	spoolBytes.WithLabelValues(s.forward).Set(float64(s.size))
+}
+
+func (s *Spool) closeFile() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func countLines(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return int64(bytes.Count(data, []byte{'\n'})), nil
+}
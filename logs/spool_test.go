@@ -0,0 +1,208 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpoolAppendAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := NewSpool(dir, "tcp://spool", types.LogSpoolConfig{SegmentBytes: 64})
+	assert.NoError(t, err)
+
+	for _, data := range []string{"a", "b", "c", "d"} {
+		assert.NoError(t, spool.Append(&types.Log{ID: "Rei", Data: data}))
+	}
+	assert.True(t, spool.Len() > 0)
+	assert.True(t, len(spool.segments) > 1)
+
+	// failed replay keeps the failed line
+	var replayed []string
+	failed := errors.New("failed")
+	err = spool.Replay(func(l *types.Log) error {
+		if l.Data == "c" {
+			return failed
+		}
+		replayed = append(replayed, l.Data)
+		return nil
+	})
+	assert.Equal(t, failed, err)
+	assert.Equal(t, []string{"a", "b"}, replayed)
+
+	// segments left on disk are loaded by a new spool
+	assert.NoError(t, spool.Close())
+	spool, err = NewSpool(dir, "tcp://spool", types.LogSpoolConfig{SegmentBytes: 64})
+	assert.NoError(t, err)
+	assert.NoError(t, spool.Append(&types.Log{ID: "Rei", Data: "e"}))
+
+	replayed = nil
+	assert.NoError(t, spool.Replay(func(l *types.Log) error {
+		replayed = append(replayed, l.Data)
+		return nil
+	}))
+	assert.Equal(t, []string{"c", "d", "e"}, replayed)
+	assert.Equal(t, int64(0), spool.Len())
+	assert.Len(t, spool.segments, 0)
+}
+
+func TestSpoolReplayBatch(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), "http://spool", types.LogSpoolConfig{})
+	assert.NoError(t, err)
+	for _, data := range []string{"a", "b", "c", "d", "e"} {
+		assert.NoError(t, spool.Append(&types.Log{ID: "Rei", Data: data}))
+	}
+
+	// a failed batch is replayed again
+	var batches [][]string
+	failed := errors.New("failed")
+	replay := func(loglines []*types.Log) error {
+		var batch []string
+		for _, l := range loglines {
+			batch = append(batch, l.Data)
+		}
+		batches = append(batches, batch)
+		if batch[0] == "c" && len(batches) == 2 {
+			return failed
+		}
+		return nil
+	}
+	assert.Equal(t, failed, spool.ReplayBatch(2, replay))
+	assert.NoError(t, spool.ReplayBatch(2, replay))
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"c", "d"}, {"e"}}, batches)
+	assert.Equal(t, int64(0), spool.Len())
+}
+
+func TestSpoolReplayChunk(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), "http://chunk", types.LogSpoolConfig{})
+	assert.NoError(t, err)
+	for _, data := range []string{"a", "b", "c", "d", "e"} {
+		assert.NoError(t, spool.Append(&types.Log{ID: "Rei", Data: data}))
+	}
+
+	var batches [][]string
+	replay := func(loglines []*types.Log) error {
+		var batch []string
+		for _, l := range loglines {
+			batch = append(batch, l.Data)
+		}
+		batches = append(batches, batch)
+		return nil
+	}
+	// at most 3 lines are replayed at a time, the lines appended between chunks are replayed after
+	assert.NoError(t, spool.ReplayChunk(2, 3, replay))
+	assert.NotZero(t, spool.Len())
+	assert.NoError(t, spool.Append(&types.Log{ID: "Rei", Data: "f"}))
+	assert.NoError(t, spool.ReplayChunk(2, 3, replay))
+	assert.Equal(t, [][]string{{"a", "b"}, {"c"}, {"d", "e"}, {"f"}}, batches)
+	assert.Equal(t, int64(0), spool.Len())
+	assert.Len(t, spool.segments, 0)
+}
+
+func TestSpoolLimits(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), "tcp://limits", types.LogSpoolConfig{MaxBytes: 400})
+	assert.NoError(t, err)
+
+	logline := &types.Log{ID: "Rei", Data: "data"}
+	data, _ := json.Marshal(logline)
+	assert.NoError(t, spool.Append(logline))
+	for spool.Len()+int64(len(data))+1 <= 400 {
+		assert.NoError(t, spool.Append(logline))
+	}
+	assert.Equal(t, common.ErrSpoolFull, spool.Append(logline))
+
+	spool.maxAge = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	assert.NoError(t, spool.Replay(func(*types.Log) error {
+		t.Fatal("expired lines should not be replayed")
+		return nil
+	}))
+	assert.Equal(t, int64(0), spool.Len())
+}
+
+func TestWriterWithSpool(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr := "tcp://127.0.0.1:34568"
+	writer, err := NewWriter(ctx, addr, &types.LogConfig{Spool: types.LogSpoolConfig{Dir: t.TempDir()}})
+	assert.NoError(t, err)
+	assert.Nil(t, writer.enc)
+
+	// spooled while disconnected
+	assert.NoError(t, writer.Write(&types.Log{Data: "0"}))
+	assert.NoError(t, writer.Write(&types.Log{Data: "1"}))
+
+	tcpL, err := net.Listen("tcp", ":34568")
+	assert.NoError(t, err)
+	defer tcpL.Close()
+
+	writer.reconnect(ctx)
+	assert.NoError(t, writer.Write(&types.Log{Data: "2"}))
+
+	conn, err := tcpL.Accept()
+	assert.NoError(t, err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for _, expected := range []string{"0", "1", "2"} {
+		line, err := reader.ReadBytes('\n')
+		assert.NoError(t, err)
+		logline := &types.Log{}
+		assert.NoError(t, json.Unmarshal(line, logline))
+		assert.Equal(t, expected, logline.Data)
+	}
+}
+
+func TestWriterReplayInBackground(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tcpL, err := net.Listen("tcp", ":34573")
+	assert.NoError(t, err)
+	defer tcpL.Close()
+
+	// logs spooled by the last run
+	addr := "tcp://127.0.0.1:34573"
+	config := &types.LogConfig{Spool: types.LogSpoolConfig{Dir: t.TempDir()}}
+	spool, err := getSpool(addr, config.Spool)
+	assert.NoError(t, err)
+	for i := 0; i < 3*replayChunkLines; i++ {
+		assert.NoError(t, spool.Append(&types.Log{Data: strconv.Itoa(i)}))
+	}
+
+	// writing is not blocked by replaying, the new line is sent after the spooled ones
+	writer, err := NewWriter(ctx, addr, config)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Write(&types.Log{Data: "new"}))
+
+	conn, err := tcpL.Accept()
+	assert.NoError(t, err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for i := 0; i <= 3*replayChunkLines; i++ {
+		expected := strconv.Itoa(i)
+		if i == 3*replayChunkLines {
+			expected = "new"
+		}
+		line, err := reader.ReadBytes('\n')
+		assert.NoError(t, err)
+		logline := &types.Log{}
+		assert.NoError(t, json.Unmarshal(line, logline))
+		assert.Equal(t, expected, logline.Data)
+	}
+	assert.Eventually(t, func() bool {
+		connected := false
+		writer.withRLock(func() { connected = writer.enc != nil })
+		return connected
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,80 @@
+package logs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"sync"
+
+	"github.com/projecteru2/agent/types"
+
+	"github.com/pkg/errors"
+	"github.com/projecteru2/core/log"
+)
+
+var (
+	tlsConfigs     = map[types.LogTLSConfig]*tls.Config{}
+	tlsConfigsLock sync.RWMutex
+)
+
+// getTLSConfig returns the tls config loaded from the files, files are only read once until ReloadTLS
+func getTLSConfig(config types.LogTLSConfig) (*tls.Config, error) {
+	tlsConfigsLock.RLock()
+	tlsConfig, ok := tlsConfigs[config]
+	tlsConfigsLock.RUnlock()
+	if ok {
+		return tlsConfig, nil
+	}
+
+	tlsConfig, err := loadTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfigsLock.Lock()
+	defer tlsConfigsLock.Unlock()
+	tlsConfigs[config] = tlsConfig
+	return tlsConfig, nil
+}
+
+// ReloadTLS reloads certificates of tls forwards, new connections will use the new ones.
+// If some of them fail, the old ones are kept.
+func ReloadTLS() {
+	tlsConfigsLock.Lock()
+	defer tlsConfigsLock.Unlock()
+
+	logger := log.WithFunc("ReloadTLS")
+	for config := range tlsConfigs {
+		tlsConfig, err := loadTLSConfig(config)
+		if err != nil {
+			logger.Errorf(nil, err, "failed to reload certificates %s, keep the old ones", config.Cert) //nolint
+			continue
+		}
+		tlsConfigs[config] = tlsConfig
+	}
+	logger.Info(nil, "certificates reloaded") //nolint
+}
+
+func loadTLSConfig(config types.LogTLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: config.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if config.CA != "" {
+		ca, err := os.ReadFile(config.CA)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.Errorf("no certificate found in %s", config.CA)
+		}
+	}
+	if config.Cert != "" || config.Key != "" {
+		cert, err := tls.LoadX509KeyPair(config.Cert, config.Key)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,167 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/projecteru2/agent/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, cn string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{cn},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+	assert.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600))
+	keyDer, err := x509.MarshalECPrivateKey(c.key)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certPath, keyPath
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+func TestNewWriterWithTLS(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	ca := newTestCert(t, "eru", nil)
+	caPath, _ := ca.write(t, dir, "ca")
+	server := newTestCert(t, "collector", ca)
+	clientCert, clientKey := newTestCert(t, "agent", ca).write(t, dir, "client")
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	tlsL, err := tls.Listen("tcp", "127.0.0.1:34570", &tls.Config{
+		Certificates: []tls.Certificate{server.tlsCertificate()},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	})
+	assert.NoError(t, err)
+	defer tlsL.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := tlsL.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, err := bufio.NewReader(conn).ReadBytes('\n')
+		if err != nil {
+			received <- err.Error()
+			return
+		}
+		logline := &types.Log{}
+		_ = json.Unmarshal(line, logline)
+		received <- logline.Data
+	}()
+
+	config := &types.LogConfig{TLS: types.LogTLSConfig{
+		CA:         caPath,
+		Cert:       clientCert,
+		Key:        clientKey,
+		ServerName: "collector",
+	}}
+	writer, err := NewWriter(ctx, "tls://127.0.0.1:34570", config)
+	assert.NoError(t, err)
+	assert.NotNil(t, writer.enc)
+	assert.NoError(t, writer.Write(&types.Log{Data: "secret"}))
+	assert.Equal(t, "secret", <-received)
+
+	// rotated certificates are picked up after reloading
+	tlsConfig, err := getTLSConfig(config.TLS)
+	assert.NoError(t, err)
+	newTestCert(t, "agent", ca).write(t, dir, "client")
+	ReloadTLS()
+	reloaded, err := getTLSConfig(config.TLS)
+	assert.NoError(t, err)
+	assert.NotEqual(t, tlsConfig.Certificates[0].Certificate, reloaded.Certificates[0].Certificate)
+
+	// broken certificates won't replace the loaded ones
+	assert.NoError(t, os.WriteFile(clientCert, []byte("broken"), 0600))
+	ReloadTLS()
+	kept, err := getTLSConfig(config.TLS)
+	assert.NoError(t, err)
+	assert.Equal(t, reloaded, kept)
+}
+
+func TestNewWriterWithTLSUntrusted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := newTestCert(t, "collector", newTestCert(t, "evil", nil))
+	tlsL, err := tls.Listen("tcp", "127.0.0.1:34571", &tls.Config{
+		Certificates: []tls.Certificate{server.tlsCertificate()},
+		MinVersion:   tls.VersionTLS12,
+	})
+	assert.NoError(t, err)
+	defer tlsL.Close()
+	go func() {
+		for {
+			conn, err := tlsL.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				_ = conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}(conn)
+		}
+	}()
+
+	writer, err := NewWriter(ctx, "tcp+tls://127.0.0.1:34571", &types.LogConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, writer.enc)
+	assert.True(t, writer.needReconnect)
+}
@@ -2,8 +2,11 @@ package logs
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
+	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,13 +26,20 @@ var KeepaliveInterval = time.Second * 30
 // CloseWaitInterval .
 var CloseWaitInterval = time.Second * 5
 
+// replayChunkLines is the max lines replayed at a time through stream encoders,
+// async encoders replay a batch at a time
+const replayChunkLines = 1000
+
 // Writer is a writer!
 type Writer struct {
 	sync.RWMutex
+	forward       string
 	addr          string
 	scheme        string
 	stdout        bool
+	config        *types.LogConfig
 	enc           Encoder
+	spool         *Spool
 	needReconnect bool
 }
 
@@ -47,7 +57,7 @@ func (d discard) Close() error {
 }
 
 // NewWriter return writer
-func NewWriter(ctx context.Context, addr string, stdout bool) (writer *Writer, err error) {
+func NewWriter(ctx context.Context, addr string, config *types.LogConfig) (writer *Writer, err error) {
 	if addr == Discard {
 		return &Writer{
 			enc: NewStreamEncoder(discard{}),
@@ -60,13 +70,14 @@ func NewWriter(ctx context.Context, addr string, stdout bool) (writer *Writer, e
 		return nil, err
 	}
 
-	writer = &Writer{addr: u.Host, scheme: u.Scheme, stdout: stdout}
+	writer = &Writer{forward: addr, addr: u.Host, scheme: u.Scheme, stdout: config.Stdout, config: config}
 	writer.enc, err = writer.createEncoder()
 
 	switch {
 	case err == common.ErrInvalidScheme:
 		logger.Infof(ctx, "create an empty writer for %s success", addr)
 		writer.enc = NewStreamEncoder(discard{})
+		return writer, nil
 	case err == common.ErrJournalDisable:
 		return nil, err
 	case err != nil:
@@ -76,7 +87,24 @@ func NewWriter(ctx context.Context, addr string, stdout bool) (writer *Writer, e
 		logger.Infof(ctx, "create writer for %s success", addr)
 	}
 
-	_ = utils.Pool.Submit(func() { writer.keepalive(ctx) })
+	// the logs spooled by the last run are sent before the new ones in background
+	var pending Encoder
+	if config.Spool.Dir != "" {
+		if writer.spool, err = getSpool(addr, config.Spool); err != nil {
+			logger.Errorf(ctx, err, "failed to open spool for %s, logs will be dropped while disconnected", addr)
+		}
+		if writer.enc != nil && writer.spool != nil && writer.spool.Len() > 0 {
+			pending, writer.enc = writer.enc, nil
+			writer.needReconnect = true
+		}
+	}
+
+	_ = utils.Pool.Submit(func() {
+		if pending != nil {
+			writer.resume(ctx, pending)
+		}
+		writer.keepalive(ctx)
+	})
 	return writer, nil
 }
 
@@ -90,15 +118,28 @@ func (w *Writer) Write(logline *types.Log) error {
 	}
 	var err error
 	w.withLock(func() {
-		if w.enc == nil {
-			err = common.ErrConnecting
-			w.needReconnect = true
+		if w.enc != nil {
+			if err = w.enc.Encode(logline); err == nil {
+				return
+			}
+			if err == common.ErrQueueFull && w.spool == nil {
+				// nowhere to keep the line, drop it and keep the encoder
+				return
+			}
+			w.checkError(err)
+		}
+		w.needReconnect = true
+		if w.spool == nil {
+			if err == nil {
+				err = common.ErrConnecting
+			}
 			return
 		}
-		err = w.enc.Encode(logline)
+		// keep the line, it will be sent after reconnecting
+		if e := w.spool.Append(logline); err == nil {
+			err = e
+		}
 	})
-
-	w.checkError(err)
 	return err
 }
 
@@ -106,14 +147,27 @@ func (w *Writer) close() error {
 	var err error
 	w.withLock(func() {
 		if w.enc != nil {
-			err = w.enc.Close()
-			w.enc = nil
+			err = w.closeEncoder(true)
 		}
 	})
 	log.WithFunc("close").Infof(nil, "writer for %s closed", w.addr) //nolint
 	return err
 }
 
+// closeEncoder closes the encoder, must be called with lock held.
+// flush sends the pending logs before closing, otherwise they are not sent.
+// The logs an async encoder didn't deliver are spooled, before the logs written after, so the order is kept.
+func (w *Writer) closeEncoder(flush bool) error {
+	var err error
+	if enc, async := w.enc.(asyncEncoder); async && !flush {
+		spoolLines(w.spool, w.forward, enc.Drain())
+	} else {
+		err = w.enc.Close()
+	}
+	w.enc = nil
+	return err
+}
+
 func (w *Writer) withLock(f func()) {
 	w.Lock()
 	defer w.Unlock()
@@ -126,12 +180,32 @@ func (w *Writer) withRLock(f func()) {
 	f()
 }
 
-func (w *Writer) createUDPEncoder() (Encoder, error) {
+func (w *Writer) dialUDP() (net.Conn, error) {
 	udpAddr, err := net.ResolveUDPAddr("udp", w.addr)
 	if err != nil {
 		return nil, err
 	}
-	conn, err := net.DialUDP("udp", nil, udpAddr)
+	return net.DialUDP("udp", nil, udpAddr)
+}
+
+func (w *Writer) dialTCP() (net.Conn, error) {
+	tcpAddr, err := net.ResolveTCPAddr("tcp", w.addr)
+	if err != nil {
+		return nil, err
+	}
+	return net.DialTCP("tcp", nil, tcpAddr)
+}
+
+func (w *Writer) dialTLS() (net.Conn, error) {
+	config, err := getTLSConfig(w.config.TLS)
+	if err != nil {
+		return nil, err
+	}
+	return tls.Dial("tcp", w.addr, config)
+}
+
+func (w *Writer) createUDPEncoder() (Encoder, error) {
+	conn, err := w.dialUDP()
 	if err != nil {
 		return nil, err
 	}
@@ -139,17 +213,86 @@ func (w *Writer) createUDPEncoder() (Encoder, error) {
 }
 
 func (w *Writer) createTCPEncoder() (Encoder, error) {
-	tcpAddr, err := net.ResolveTCPAddr("tcp", w.addr)
+	conn, err := w.dialTCP()
 	if err != nil {
 		return nil, err
 	}
-	conn, err := net.DialTCP("tcp", nil, tcpAddr)
+	return NewStreamEncoder(conn), nil
+}
+
+func (w *Writer) createTLSEncoder() (Encoder, error) {
+	conn, err := w.dialTLS()
 	if err != nil {
 		return nil, err
 	}
 	return NewStreamEncoder(conn), nil
 }
 
+// createSyslogEncoder uses octet counting framing over stream transports
+func (w *Writer) createSyslogEncoder() (Encoder, error) {
+	var conn net.Conn
+	var err error
+	octetCounting := true
+	switch w.scheme {
+	case "syslog+tcp":
+		conn, err = w.dialTCP()
+	case "syslog+tls":
+		conn, err = w.dialTLS()
+	default:
+		conn, err = w.dialUDP()
+		octetCounting = false
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewSyslogEncoder(conn, octetCounting), nil
+}
+
+// createHTTPEncoder returns the encoder shared by the writers of the forward,
+// so logs of workloads are sent in the same batches
+func (w *Writer) createHTTPEncoder() (Encoder, error) {
+	enc, err := getEncoder(w.forward, func() (asyncEncoder, error) {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if w.scheme == "https" {
+			config, err := getTLSConfig(w.config.TLS)
+			if err != nil {
+				return nil, err
+			}
+			transport.TLSClientConfig = config
+		}
+		client := &http.Client{Transport: transport, Timeout: w.config.HTTP.Timeout}
+		enc, err := NewHTTPEncoder(w.forward, client, w.config.HTTP)
+		if err != nil {
+			return nil, err
+		}
+		return enc, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
+
+// createKafkaEncoder parses forwards like kafka://broker1:9092,broker2:9092/topic,
+// it returns the encoder shared by the writers of the forward, so there is one producer per topic
+func (w *Writer) createKafkaEncoder() (Encoder, error) {
+	enc, err := getEncoder(w.forward, func() (asyncEncoder, error) {
+		u, err := url.Parse(w.forward)
+		if err != nil {
+			return nil, err
+		}
+		enc, err := NewKafkaEncoder(strings.Split(u.Host, ","), strings.TrimPrefix(u.Path, "/"), w.config.Kafka)
+		if err != nil {
+			return nil, err
+		}
+		return enc, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
+
 // CreateConn create conn
 func (w *Writer) createEncoder() (enc Encoder, err error) {
 	switch w.scheme {
@@ -157,6 +300,14 @@ func (w *Writer) createEncoder() (enc Encoder, err error) {
 		enc, err = w.createUDPEncoder()
 	case "tcp":
 		enc, err = w.createTCPEncoder()
+	case "tls", "tcp+tls":
+		enc, err = w.createTLSEncoder()
+	case "syslog", "syslog+udp", "syslog+tcp", "syslog+tls":
+		enc, err = w.createSyslogEncoder()
+	case "http", "https":
+		enc, err = w.createHTTPEncoder()
+	case "kafka":
+		enc, err = w.createKafkaEncoder()
 	case "journal":
 		enc, err = CreateJournalEncoder()
 	default:
@@ -166,7 +317,7 @@ func (w *Writer) createEncoder() (enc Encoder, err error) {
 	return enc, err
 }
 
-func (w *Writer) reconnect() {
+func (w *Writer) reconnect(ctx context.Context) {
 	needReconnect := false
 	w.withRLock(func() {
 		needReconnect = w.needReconnect
@@ -178,15 +329,50 @@ func (w *Writer) reconnect() {
 
 	logger.Debugf(nil, "Reconnecting to %s...", w.addr) //nolint
 	enc, err := w.createEncoder()
-	if err == nil {
+	if err != nil {
+		logger.Warnf(nil, "Failed to connect to %s: %s", w.addr, err) //nolint
+		return
+	}
+	if w.resume(ctx, enc) {
+		logger.Debugf(nil, "Connect to %s successfully", w.addr) //nolint
+	}
+}
+
+// resume replays the spool through enc in chunks, then writes through enc, returns false if replaying fails.
+// The lock is not held while replaying, the lines written meanwhile are spooled behind the replayed ones,
+// and enc is used only after the spool is empty, so the order is kept.
+func (w *Writer) resume(ctx context.Context, enc Encoder) bool {
+	for {
+		done := false
 		w.withLock(func() {
-			w.enc = enc
-			w.needReconnect = false
+			if w.spool == nil || w.spool.Len() == 0 {
+				w.enc = enc
+				w.needReconnect = false
+				done = true
+			}
 		})
-		logger.Debugf(nil, "Connect to %s successfully", w.addr) //nolint
-		return
+		if done {
+			return true
+		}
+		err := ctx.Err()
+		if err == nil {
+			err = w.replayChunk(enc)
+		}
+		if err != nil {
+			log.WithFunc("resume").Warnf(nil, "Failed to replay spooled logs to %s: %s", w.addr, err) //nolint
+			_ = enc.Close()
+			return false
+		}
+	}
+}
+
+// replayChunk sends a chunk of spooled logs through enc.
+// Async encoders deliver the spooled logs before they are removed from spool.
+func (w *Writer) replayChunk(enc Encoder) error {
+	if enc, ok := enc.(asyncEncoder); ok {
+		return w.spool.ReplayChunk(enc.BatchSize(), enc.BatchSize(), enc.Deliver)
 	}
-	logger.Warnf(nil, "Failed to connect to %s: %s", w.addr, err) //nolint
+	return w.spool.ReplayChunk(1, replayChunkLines, func(loglines []*types.Log) error { return enc.Encode(loglines[0]) })
 }
 
 func (w *Writer) keepalive(ctx context.Context) {
@@ -194,7 +380,7 @@ func (w *Writer) keepalive(ctx context.Context) {
 	for {
 		select {
 		case <-timer.C:
-			w.reconnect()
+			w.reconnect(ctx)
 			timer.Reset(KeepaliveInterval)
 		case <-ctx.Done():
 			// leave some time for the pending writing
@@ -207,15 +393,13 @@ func (w *Writer) keepalive(ctx context.Context) {
 	}
 }
 
+// checkError stops the encoder failed to encode, must be called with lock held.
+// If the queue of an async encoder is full, it's stopped too, so the queued logs are spooled before the new ones,
+// it's just released if it's a shared encoder closed by another writer.
 func (w *Writer) checkError(err error) {
-	if err != nil && err != common.ErrConnecting {
+	if err != common.ErrQueueFull && err != common.ErrEncoderClosed {
 		log.WithFunc("checkError").Error(nil, err, "Sending log failed") //nolint
-		w.withLock(func() {
-			if w.enc != nil {
-				w.enc.Close()
-				w.enc = nil
-				w.needReconnect = true
-			}
-		})
 	}
+	_ = w.closeEncoder(false)
+	w.needReconnect = true
 }
@@ -17,7 +17,7 @@ func TestNewWriterWithUDP(t *testing.T) {
 	defer cancel()
 	// udp writer
 	addr := "udp://127.0.0.1:23456"
-	w, err := NewWriter(ctx, addr, true)
+	w, err := NewWriter(ctx, addr, &types.LogConfig{Stdout: true})
 	assert.NoError(t, err)
 	assert.NoError(t, w.Write(&types.Log{}))
 }
@@ -30,7 +30,7 @@ func TestNewWriterWithTCP(t *testing.T) {
 	tcpL, err := net.Listen("tcp", ":34567")
 	defer tcpL.Close()
 	addr := "tcp://127.0.0.1:34567"
-	w, err := NewWriter(ctx, addr, true)
+	w, err := NewWriter(ctx, addr, &types.LogConfig{Stdout: true})
 	assert.NoError(t, err)
 	assert.NoError(t, w.Write(&types.Log{}))
 }
@@ -46,7 +46,7 @@ func TestNewWriterWithJournal(t *testing.T) {
 	assert.NoError(t, err)
 	defer enc.Close()
 
-	w, err := NewWriter(ctx, addr, true)
+	w, err := NewWriter(ctx, addr, &types.LogConfig{Stdout: true})
 	assert.NoError(t, err)
 
 	w.enc = enc
@@ -81,7 +81,7 @@ func TestNewWriters(t *testing.T) {
 
 	for addr, expectedErr := range cases {
 		go func(addr string, expectedErr error) {
-			writer, err := NewWriter(ctx, addr, false)
+			writer, err := NewWriter(ctx, addr, &types.LogConfig{})
 			assert.Equal(t, expectedErr, err)
 			if expectedErr != nil {
 				return
@@ -100,7 +100,7 @@ func TestReconnect(t *testing.T) {
 	defer cancel()
 
 	addr := "tcp://127.0.0.1:34567"
-	writer, err := NewWriter(ctx, addr, false)
+	writer, err := NewWriter(ctx, addr, &types.LogConfig{})
 	assert.NoError(t, err)
 	assert.Nil(t, writer.enc)
 	assert.Equal(t, writer.needReconnect, true)
@@ -109,6 +109,43 @@ func TestReconnect(t *testing.T) {
 	assert.NoError(t, err)
 	defer tcpL.Close()
 
-	writer.reconnect()
+	writer.reconnect(ctx)
 	assert.NoError(t, writer.Write(&types.Log{}))
 }
+
+func TestFanoutWriter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tcpL, err := net.Listen("tcp", ":34569")
+	assert.NoError(t, err)
+	defer tcpL.Close()
+
+	writer, err := NewFanoutWriter(ctx, []string{"udp://127.0.0.1:23456", "tcp://127.0.0.1:34569"}, &types.LogConfig{Stdout: true})
+	assert.NoError(t, err)
+	assert.Len(t, writer.writers, 2)
+	assert.True(t, writer.writers[0].stdout)
+	assert.False(t, writer.writers[1].stdout)
+	assert.NoError(t, writer.Write(&types.Log{}))
+
+	// no forwards, discard
+	writer, err = NewFanoutWriter(ctx, nil, &types.LogConfig{})
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Write(&types.Log{}))
+}
+
+func TestNewWriterWithSyslog(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tcpL, err := net.Listen("tcp", ":34572")
+	assert.NoError(t, err)
+	defer tcpL.Close()
+
+	for _, addr := range []string{"syslog://127.0.0.1:23456", "syslog+udp://127.0.0.1:23456", "syslog+tcp://127.0.0.1:34572"} {
+		w, err := NewWriter(ctx, addr, &types.LogConfig{})
+		assert.NoError(t, err)
+		assert.IsType(t, &SyslogEncoder{}, w.enc)
+		assert.NoError(t, w.Write(&types.Log{}))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"io"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -19,19 +20,11 @@ import (
 func (m *Manager) attach(ctx context.Context, ID string) {
 	logger := log.WithFunc("attach").WithField("ID", ID)
 	logger.Debug(ctx, "attaching workload")
+	// not deduplicated here, the attach of a restarted workload may run while the last one is tearing down
+	defer m.states.beginAttach(ID)()
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	transfer := m.forwards.Get(ID, 0)
-	if transfer == "" {
-		transfer = logs.Discard
-	}
-	writer, err := logs.NewWriter(ctx, transfer, m.config.Log.Stdout)
-	if err != nil {
-		logger.Errorf(ctx, err, "create log forward %s failed", transfer)
-		return
-	}
-
 	// get app info
 	workloadName, err := m.runtimeClient.GetWorkloadName(ctx, ID)
 	if err != nil {
@@ -49,6 +42,31 @@ func (m *Manager) attach(ctx context.Context, ID string) {
 		return
 	}
 
+	extra, err := m.runtimeClient.LogFieldsExtra(ctx, ID)
+	if err != nil {
+		logger.Error(ctx, err, "failed to get log fields extra")
+	}
+
+	labels, err := m.runtimeClient.GetWorkloadLabels(ctx, ID)
+	if err != nil {
+		logger.Error(ctx, err, "failed to get workload labels")
+	}
+	multiline, err := m.getMultilineConfig(labels)
+	if err != nil {
+		logger.Error(ctx, err, "failed to get multiline config")
+	}
+	parser, err := m.getParser(labels)
+	if err != nil {
+		logger.Error(ctx, err, "failed to get log parser")
+	}
+
+	forwards := m.router.Get(ID, &types.Log{ID: ID, Name: name, EntryPoint: entryPoint, Extra: extra}, labels)
+	writer, err := logs.NewFanoutWriter(ctx, forwards, &m.config.Log)
+	if err != nil {
+		logger.Errorf(ctx, err, "create log forwards %v failed", forwards)
+		return
+	}
+
 	// attach workload
 	outr, errr, err := m.runtimeClient.AttachWorkload(ctx, ID)
 	if err != nil {
@@ -56,13 +74,27 @@ func (m *Manager) attach(ctx context.Context, ID string) {
 		return
 	}
 	logger.Infof(ctx, "attach %s workload success", workloadName)
+	m.states.attached(ID, true)
+	defer m.states.attached(ID, false)
 
 	// attach metrics
 	_ = utils.Pool.Submit(func() { m.runtimeClient.CollectWorkloadMetrics(ctx, ID) })
 
-	extra, err := m.runtimeClient.LogFieldsExtra(ctx, ID)
+	send := func(l *types.Log) {
+		if m.logBroadcaster != nil {
+			m.logBroadcaster.publish(l)
+		}
+		if err := writer.Write(l); err != nil && !(entryPoint == "agent" && utils.IsDockerized()) {
+			logger.Errorf(ctx, err, "%s workload %s write failed", workloadName, entryPoint)
+		}
+	}
+	// the limiter is shared by stdout and stderr
+	limiter, err := m.getLimiter(labels, send)
 	if err != nil {
-		logger.Error(ctx, err, "failed to get log fields extra")
+		logger.Error(ctx, err, "failed to get log rate limiter")
+	}
+	if limiter != nil {
+		defer limiter.Close()
 	}
 
 	wg := &sync.WaitGroup{}
@@ -71,6 +103,29 @@ func (m *Manager) attach(ctx context.Context, ID string) {
 		logger.Debugf(ctx, "attach pump %s %s start", workloadName, typ)
 		defer logger.Debugf(ctx, "attach pump %s %s finished", workloadName, typ)
 
+		emit := func(l *types.Log) {
+			if limiter != nil && !limiter.Allow(l) {
+				return
+			}
+			// redacted before parsing, so the secrets are not promoted into extra fields
+			if m.redactor != nil {
+				l = m.redactor.Redact(l)
+			}
+			if parser != nil {
+				l = parser.Parse(l)
+			}
+			send(l)
+		}
+		if multiline != nil {
+			merger, err := logs.NewMerger(*multiline, emit)
+			if err != nil {
+				logger.Error(ctx, err, "invalid multiline config")
+			} else {
+				defer merger.Close()
+				emit = merger.Add
+			}
+		}
+
 		buf := bufio.NewReader(source)
 		for {
 			data, err := buf.ReadString('\n')
@@ -92,12 +147,7 @@ func (m *Manager) attach(ctx context.Context, ID string) {
 				Datetime:   time.Now().Format(common.DateTimeFormat),
 				Extra:      extra,
 			}
-			if m.logBroadcaster != nil && m.logBroadcaster.logC != nil {
-				m.logBroadcaster.logC <- l
-			}
-			if err := writer.Write(l); err != nil && !(entryPoint == "agent" && utils.IsDockerized()) {
-				logger.Errorf(ctx, err, "%s workload %s write failed", workloadName, entryPoint)
-			}
+			emit(l)
 		}
 	}
 	wg.Add(2)
@@ -105,3 +155,53 @@ func (m *Manager) attach(ctx context.Context, ID string) {
 	_ = utils.Pool.Submit(func() { pump("stdout", outr) })
 	_ = utils.Pool.Submit(func() { pump("stderr", errr) })
 }
+
+// getMultilineConfig returns the multiline rule picked by the workload's label, nil if there is no such label
+func (m *Manager) getMultilineConfig(labels map[string]string) (*types.LogMultilineConfig, error) {
+	name, ok := labels[common.ERULogMultiline]
+	if !ok {
+		return nil, nil
+	}
+	config, ok := m.config.Log.Multiline[name]
+	if !ok {
+		return nil, common.ErrMultilineNotFound
+	}
+	return &config, nil
+}
+
+// getParser returns the parser picked by the workload's label, nil if there is no such label
+func (m *Manager) getParser(labels map[string]string) (*logs.Parser, error) {
+	format, ok := labels[common.ERULogParser]
+	if !ok {
+		return nil, nil
+	}
+	promote := m.config.Log.Parser.Promote
+	if keys := labels[common.ERULogPromote]; keys != "" {
+		promote = strings.Split(keys, ",")
+	}
+	return logs.NewParser(format, promote)
+}
+
+// getLimiter returns the rate limiter of logs, the global config can be overridden by the workload's labels,
+// nil if logs are not limited
+func (m *Manager) getLimiter(labels map[string]string, emit func(*types.Log)) (*logs.Limiter, error) {
+	config := m.config.Log.RateLimit
+	if value, ok := labels[common.ERULogRate]; ok {
+		r, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, common.ErrInvalidRateLimit
+		}
+		config.Rate = r
+	}
+	if value, ok := labels[common.ERULogBurst]; ok {
+		burst, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, common.ErrInvalidRateLimit
+		}
+		config.Burst = burst
+	}
+	if mode, ok := labels[common.ERULogRateMode]; ok {
+		config.Mode = mode
+	}
+	return logs.NewLimiter(config, emit)
+}
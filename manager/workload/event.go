@@ -14,11 +14,18 @@ import (
 type EventHandler struct {
 	sync.Mutex
 	handlers map[string]func(context.Context, *types.WorkloadEventMessage)
+	watchers map[chan *types.WorkloadEventMessage]struct{}
 }
 
+// eventWatcherBuffer is the number of events waiting for each watcher, more events are dropped
+const eventWatcherBuffer = 100
+
 // NewEventHandler new a event handler
 func NewEventHandler() *EventHandler {
-	return &EventHandler{handlers: make(map[string]func(context.Context, *types.WorkloadEventMessage))}
+	return &EventHandler{
+		handlers: make(map[string]func(context.Context, *types.WorkloadEventMessage)),
+		watchers: make(map[chan *types.WorkloadEventMessage]struct{}),
+	}
 }
 
 // Handle hand a event
@@ -28,6 +35,22 @@ func (e *EventHandler) Handle(action string, h func(context.Context, *types.Work
 	e.handlers[action] = h
 }
 
+// Subscribe returns a channel of the watched events, it's closed when ctx is done
+func (e *EventHandler) Subscribe(ctx context.Context) <-chan *types.WorkloadEventMessage {
+	c := make(chan *types.WorkloadEventMessage, eventWatcherBuffer)
+	e.Lock()
+	defer e.Unlock()
+	e.watchers[c] = struct{}{}
+	_ = utils.Pool.Submit(func() {
+		<-ctx.Done()
+		e.Lock()
+		defer e.Unlock()
+		delete(e.watchers, c)
+		close(c)
+	})
+	return c
+}
+
 // Watch watch change
 func (e *EventHandler) Watch(ctx context.Context, c <-chan *types.WorkloadEventMessage) {
 	logger := log.WithFunc("Watch")
@@ -43,6 +66,13 @@ func (e *EventHandler) Watch(ctx context.Context, c <-chan *types.WorkloadEventM
 			if h := e.handlers[ev.Action]; h != nil {
 				_ = utils.Pool.Submit(func() { h(ctx, ev) })
 			}
+			for c := range e.watchers {
+				select {
+				case c <- ev:
+				default:
+					logger.Warnf(ctx, "event of workload %s dropped, the watcher is too slow", ev.ID)
+				}
+			}
 			e.Unlock()
 		case <-ctx.Done():
 			logger.Info(ctx, "context canceled, stop watching")
@@ -24,6 +24,7 @@ func TestEvent(t *testing.T) {
 	assertInitStatus(t, store)
 
 	go manager.monitor(ctx)
+	events := manager.WatchEvents(ctx)
 
 	// starts the events: Shinji 400%, Asuka starts, Asuka dies, Rei dies
 	go runtime.StartEvents()
@@ -46,4 +47,12 @@ func TestEvent(t *testing.T) {
 		Running: true,
 		Healthy: true,
 	})
+
+	// the events are re-emitted to watchers
+	actions := []string{}
+	for len(events) > 0 {
+		event := <-events
+		actions = append(actions, event.ID+":"+event.Action)
+	}
+	assert.Equal(t, []string{"Shinji:400%", "Asuka:start", "Asuka:die", "Rei:die"}, actions)
 }
@@ -2,66 +2,243 @@ package workload
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/projecteru2/agent/common"
 	"github.com/projecteru2/agent/types"
 	"github.com/projecteru2/agent/utils"
 
 	"github.com/projecteru2/core/log"
 )
 
+const (
+	healthCheckTick    = time.Second
+	defaultMaxInFlight = 64
+)
+
+// healthCheck checks each workload every its interval, the interval can be overridden by label.
+// The first check of a workload is delayed randomly in the interval,
+// so the checks are spread over the interval instead of all at once.
+// The workloads are listed every global interval, to pick the new ones and forget the removed ones.
 func (m *Manager) healthCheck(ctx context.Context) {
-	tick := time.NewTicker(time.Duration(m.config.HealthCheck.Interval) * time.Second)
+	interval := time.Duration(m.config.HealthCheck.Interval) * time.Second
+	refresh := time.NewTicker(interval)
+	defer refresh.Stop()
+	tick := time.NewTicker(healthCheckTick)
 	defer tick.Stop()
 
+	// next check time of workloads, a workload is checked again an interval after its last check completes,
+	// and it's skipped while its check is in flight, so slow checks won't pile up
+	schedule := map[string]time.Time{}
+	inFlight := map[string]bool{}
+	completed := make(chan string)
+	m.scheduleWorkloads(ctx, schedule)
 	for {
 		select {
-		case <-tick.C:
-			_ = utils.Pool.Submit(func() { m.checkAllWorkloads(ctx) })
+		case <-refresh.C:
+			m.scheduleWorkloads(ctx, schedule)
+		case now := <-tick.C:
+			for ID, next := range schedule {
+				if inFlight[ID] || now.Before(next) {
+					continue
+				}
+				ID := ID
+				inFlight[ID] = true
+				if err := utils.Pool.Submit(func() {
+					m.checkOneWorkload(ctx, ID)
+					select {
+					case completed <- ID:
+					case <-ctx.Done():
+					}
+				}); err != nil {
+					log.WithFunc("healthCheck").WithField("ID", ID).Error(ctx, err, "failed to submit health check")
+					delete(inFlight, ID)
+					schedule[ID] = now.Add(m.getCheckInterval(ID))
+				}
+			}
+		case ID := <-completed:
+			delete(inFlight, ID)
+			// the workload may be removed while checking
+			if _, ok := schedule[ID]; ok {
+				schedule[ID] = time.Now().Add(m.getCheckInterval(ID))
+			}
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// scheduleWorkloads adds the new workloads into schedule with a random delay, and removes the gone ones
 // 检查全部 label 为ERU=1的workload
 // 这里需要 list all，原因是 monitor 检测到 die 的时候已经标记为 false 了
 // 但是这时候 health check 刚返回 true 回来并写入 core
 // 为了保证最终数据一致性这里也要检测
-func (m *Manager) checkAllWorkloads(ctx context.Context) {
-	logger := log.WithFunc("checkAllWorkloads")
+func (m *Manager) scheduleWorkloads(ctx context.Context, schedule map[string]time.Time) {
+	logger := log.WithFunc("scheduleWorkloads")
 	logger.Debug(ctx, "health check begin")
 	workloadIDs, err := m.runtimeClient.ListWorkloadIDs(ctx, m.getBaseFilter())
 	if err != nil {
 		logger.Error(ctx, err, "error when list all workloads with label \"ERU=1\"")
 		return
 	}
+	// forget the removed workloads
+	m.states.retain(workloadIDs)
+	m.logBroadcaster.retainHistories(workloadIDs)
 
-	for _, workloadID := range workloadIDs {
-		ID := workloadID
-		_ = utils.Pool.Submit(func() { m.checkOneWorkload(ctx, ID) })
+	now := time.Now()
+	exists := map[string]bool{}
+	for _, ID := range workloadIDs {
+		exists[ID] = true
+		if _, ok := schedule[ID]; !ok {
+			schedule[ID] = now.Add(jitter(m.getCheckInterval(ID)))
+		}
+	}
+	for ID := range schedule {
+		if !exists[ID] {
+			delete(schedule, ID)
+		}
+	}
+}
+
+// getCheckInterval returns the interval of health check, the config can be overridden by the workload's label,
+// which is known after the workload is checked
+func (m *Manager) getCheckInterval(ID string) time.Duration {
+	interval := m.config.HealthCheck.Interval
+	if state, ok := m.states.get(ID); ok && state.Status != nil && len(state.Status.Extension) > 0 {
+		labels := map[string]string{}
+		if err := json.Unmarshal(state.Status.Extension, &labels); err == nil {
+			if value, ok := labels[common.ERUHealthCheckInterval]; ok {
+				if i, err := strconv.Atoi(value); err == nil && i > 0 {
+					interval = i
+				} else {
+					log.WithFunc("getCheckInterval").WithField("ID", ID).Warnf(nil, "invalid health check interval %s", value) //nolint
+				}
+			}
+		}
 	}
+	return time.Duration(interval) * time.Second
+}
+
+// jitter returns a random duration in [0, d)
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	n, _ := rand.Int(rand.Reader, big.NewInt(int64(d)))
+	return time.Duration(n.Int64())
+}
+
+// probe gets the status of workload with health checked,
+// the probes running at the same time are limited
+func (m *Manager) probe(ctx context.Context, ID string) (*types.WorkloadStatus, error) {
+	select {
+	case m.probes <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	probesInFlight.Inc()
+	defer func() {
+		<-m.probes
+		probesInFlight.Dec()
+	}()
+
+	start := time.Now()
+	status, err := m.runtimeClient.GetStatus(ctx, ID, true)
+	result := "error"
+	if err == nil {
+		result = "unhealthy"
+		if status.Healthy {
+			result = "healthy"
+		}
+	}
+	probeDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+	return status, err
 }
 
 // 检查并保存一个workload的状态，最后返回workload是否healthy。
 // 返回healthy是为了重试用的，没啥别的意义。
 func (m *Manager) checkOneWorkload(ctx context.Context, ID string) bool {
-	logger := log.WithFunc("checkOneWorkload").WithField("ID", ID)
-	workloadStatus, err := m.runtimeClient.GetStatus(ctx, ID, true)
+	workloadStatus, err := m.CheckWorkload(ctx, ID)
+	return err == nil && workloadStatus.Healthy
+}
+
+// CheckWorkload checks the health of a workload and reports its status to store,
+// an error is returned only if the status can't be got, failures of reporting are recorded in its state
+func (m *Manager) CheckWorkload(ctx context.Context, ID string) (*types.WorkloadStatus, error) {
+	logger := log.WithFunc("CheckWorkload").WithField("ID", ID)
+	workloadStatus, err := m.probe(ctx, ID)
 	if err != nil {
 		logger.Error(ctx, err, "failed to get status of workload")
-		return false
+		return nil, err
 	}
+	workloadStatus = m.states.checked(workloadStatus, m.config.HealthCheck)
 
 	if err = m.setWorkloadStatus(ctx, workloadStatus); err != nil {
 		logger.Error(ctx, err, "update workload status failed")
 	}
-	return workloadStatus.Healthy
+	return workloadStatus, nil
+}
+
+// CheckManagedWorkload checks the workload like CheckWorkload, if it's listed with the same filter as the other workloads,
+// so the workloads not managed by this agent can't be checked through it
+func (m *Manager) CheckManagedWorkload(ctx context.Context, ID string) (*types.WorkloadStatus, error) {
+	workloadIDs, err := m.runtimeClient.ListWorkloadIDs(ctx, m.getBaseFilter())
+	if err != nil {
+		log.WithFunc("CheckManagedWorkload").Error(ctx, err, "failed to list workloads")
+		return nil, err
+	}
+	for _, workloadID := range workloadIDs {
+		if workloadID == ID {
+			return m.CheckWorkload(ctx, ID)
+		}
+	}
+	return nil, common.ErrWorkloadNotFound
+}
+
+// Reconcile checks and reports all workloads, and attaches the running ones whose logs are not attached,
+// the attached logs live with the context of manager, instead of ctx
+func (m *Manager) Reconcile(ctx context.Context) error {
+	logger := log.WithFunc("Reconcile")
+	workloadIDs, err := m.runtimeClient.ListWorkloadIDs(ctx, m.getBaseFilter())
+	if err != nil {
+		logger.Error(ctx, err, "failed to list workloads")
+		return err
+	}
+	m.states.retain(workloadIDs)
+	m.logBroadcaster.retainHistories(workloadIDs)
+
+	wg := &sync.WaitGroup{}
+	for _, workloadID := range workloadIDs {
+		wg.Add(1)
+		ID := workloadID
+		_ = utils.Pool.Submit(func() {
+			defer wg.Done()
+			workloadStatus, err := m.CheckWorkload(ctx, ID)
+			// the workloads being attached are skipped, so their logs are not attached twice
+			if err == nil && workloadStatus.Running && !m.states.isAttaching(ID) {
+				_ = utils.Pool.Submit(func() { m.attach(m.ctx, ID) })
+			}
+		})
+	}
+	wg.Wait()
+	return nil
 }
 
 // 设置workload状态，允许重试，带timeout控制
 func (m *Manager) setWorkloadStatus(ctx context.Context, status *types.WorkloadStatus) error {
 	return utils.BackoffRetry(ctx, 3, func() error {
-		return m.store.SetWorkloadStatus(ctx, status, m.config.GetHealthCheckStatusTTL())
+		return m.reportWorkloadStatus(ctx, status)
 	})
 }
+
+// reportWorkloadStatus reports workload status to store, and records the result
+func (m *Manager) reportWorkloadStatus(ctx context.Context, status *types.WorkloadStatus) error {
+	err := m.store.SetWorkloadStatus(ctx, status, m.config.GetHealthCheckStatusTTL())
+	m.states.reported(status, err)
+	return err
+}
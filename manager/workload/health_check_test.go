@@ -2,18 +2,190 @@ package workload
 
 import (
 	"context"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	runtimemocks "github.com/projecteru2/agent/runtime/mocks"
 	"github.com/projecteru2/agent/store/mocks"
+	"github.com/projecteru2/agent/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestHealthCheck(t *testing.T) {
 	manager := newMockWorkloadManager(t)
-	ctx := context.Background()
-	manager.checkAllWorkloads(ctx)
+	manager.config.HealthCheck.Interval = 1
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go manager.healthCheck(ctx)
 	store := manager.store.(*mocks.MockStore)
-	time.Sleep(2 * time.Second)
+	time.Sleep(3 * time.Second)
 
 	assertInitStatus(t, store)
 }
+
+func TestHealthCheckInFlight(t *testing.T) {
+	manager := newMockWorkloadManager(t)
+	manager.config.HealthCheck.Interval = 1
+	runtime := &runtimemocks.Runtime{}
+	manager.runtimeClient = runtime
+	runtime.On("ListWorkloadIDs", mock.Anything, mock.Anything).Return([]string{"Rei"}, nil)
+
+	// the check is slower than the interval
+	var checks, running, overlapped int32
+	runtime.On("GetStatus", mock.Anything, "Rei", true).Return(func(context.Context, string, bool) *types.WorkloadStatus {
+		atomic.AddInt32(&checks, 1)
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.AddInt32(&overlapped, 1)
+		}
+		time.Sleep(2500 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return &types.WorkloadStatus{ID: "Rei", Running: true, Healthy: true}
+	}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go manager.healthCheck(ctx)
+	time.Sleep(5 * time.Second)
+
+	// the next check waits for the last one to complete
+	assert.Equal(t, int32(0), atomic.LoadInt32(&overlapped))
+	assert.LessOrEqual(t, atomic.LoadInt32(&checks), int32(2))
+}
+
+func TestHealthCheckSchedule(t *testing.T) {
+	manager := newMockWorkloadManager(t)
+	ctx := context.Background()
+
+	schedule := map[string]time.Time{"Kaworu": time.Now()}
+	manager.scheduleWorkloads(ctx, schedule)
+	assert.Len(t, schedule, 3)
+	assert.NotContains(t, schedule, "Kaworu")
+	for _, next := range schedule {
+		assert.WithinDuration(t, time.Now().Add(5*time.Second), next, 5*time.Second)
+	}
+
+	// the interval is overridden by label after checked
+	assert.Equal(t, 10*time.Second, manager.getCheckInterval("Rei"))
+	manager.states.checked(&types.WorkloadStatus{ID: "Rei", Extension: []byte(`{"eru.healthcheck.interval":"3"}`)}, manager.config.HealthCheck)
+	assert.Equal(t, 3*time.Second, manager.getCheckInterval("Rei"))
+	manager.states.checked(&types.WorkloadStatus{ID: "Rei", Extension: []byte(`{"eru.healthcheck.interval":"soon"}`)}, manager.config.HealthCheck)
+	assert.Equal(t, 10*time.Second, manager.getCheckInterval("Rei"))
+
+	assert.Equal(t, time.Duration(0), jitter(0))
+	for i := 0; i < 100; i++ {
+		d := jitter(time.Second)
+		assert.True(t, d >= 0 && d < time.Second)
+	}
+}
+
+func TestProbeLimit(t *testing.T) {
+	manager := newMockWorkloadManager(t)
+	assert.Equal(t, defaultMaxInFlight, cap(manager.probes))
+	manager.probes = make(chan struct{}, 1)
+
+	status, err := manager.probe(context.Background(), "Shinji")
+	assert.NoError(t, err)
+	assert.True(t, status.Healthy)
+
+	// the probe waits for the running one
+	manager.probes <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = manager.probe(ctx, "Shinji")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	<-manager.probes
+	_, err = manager.probe(context.Background(), "Shinji")
+	assert.NoError(t, err)
+}
+
+func TestReconcile(t *testing.T) {
+	manager := newMockWorkloadManager(t)
+	ctx := context.Background()
+
+	// a workload being attached is not attached again
+	release := manager.states.beginAttach("Rei")
+	assert.Nil(t, manager.Reconcile(ctx))
+	assertInitStatus(t, manager.store.(*mocks.MockStore))
+	time.Sleep(time.Second)
+	state, ok := manager.GetWorkloadState("Rei")
+	assert.True(t, ok)
+	assert.True(t, state.AttachedAt.IsZero())
+	state, ok = manager.GetWorkloadState("Shinji")
+	assert.True(t, ok)
+	assert.False(t, state.AttachedAt.IsZero())
+
+	// the logs detached are attached again
+	release()
+	assert.Nil(t, manager.Reconcile(ctx))
+	time.Sleep(time.Second)
+	state, _ = manager.GetWorkloadState("Rei")
+	assert.False(t, state.AttachedAt.IsZero())
+}
+
+func TestAttachWhileTearingDown(t *testing.T) {
+	manager := newMockWorkloadManager(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the attach of a restarted workload is not dropped while the last one is tearing down
+	release := manager.states.beginAttach("Rei")
+	defer release()
+	go manager.attach(ctx, "Rei")
+	assert.Eventually(t, func() bool {
+		state, ok := manager.GetWorkloadState("Rei")
+		return ok && !state.AttachedAt.IsZero()
+	}, 3*time.Second, 100*time.Millisecond)
+}
+
+func TestHealthCheckThresholds(t *testing.T) {
+	states := newWorkloadStates()
+	config := types.HealthCheckConfig{HealthyThreshold: 2, UnhealthyThreshold: 3}
+	check := func(running, healthy bool) bool {
+		return states.checked(&types.WorkloadStatus{ID: "Rei", Running: running, Healthy: healthy}, config).Healthy
+	}
+
+	// the first probe is trusted
+	assert.True(t, check(true, true))
+	// flaps are damped
+	assert.True(t, check(true, false))
+	assert.True(t, check(true, false))
+	assert.True(t, check(true, true))
+	assert.True(t, check(true, false))
+	assert.True(t, check(true, false))
+	assert.False(t, check(true, false))
+	state, _ := states.get("Rei")
+	assert.Equal(t, 3, state.Failures)
+	assert.False(t, check(true, true))
+	assert.True(t, check(true, true))
+	state, _ = states.get("Rei")
+	assert.Equal(t, 2, state.Successes)
+
+	// a dead workload is unhealthy at once, and has to pass the healthy threshold after started
+	assert.False(t, check(false, false))
+	states.started("Rei")
+	assert.False(t, check(true, true))
+	assert.True(t, check(true, true))
+
+	// failures in start period are not counted
+	config.StartPeriod = time.Hour
+	assert.False(t, check(false, false))
+	states.started("Rei")
+	assert.False(t, check(true, false))
+	assert.False(t, check(true, true))
+	assert.False(t, check(true, false))
+	assert.False(t, check(true, true))
+	assert.True(t, check(true, true))
+	for i := 0; i < 5; i++ {
+		assert.True(t, check(true, false))
+	}
+	state, _ = states.get("Rei")
+	assert.Equal(t, 0, state.Failures)
+
+	// thresholds of zero mean 1
+	config = types.HealthCheckConfig{}
+	assert.False(t, check(true, false))
+	assert.True(t, check(true, true))
+}
@@ -45,11 +45,12 @@ func (m *Manager) initWorkloadStatus(ctx context.Context) error {
 		ID := workloadID
 		_ = utils.Pool.Submit(func() {
 			defer wg.Done()
-			workloadStatus, err := m.runtimeClient.GetStatus(ctx, ID, true)
+			workloadStatus, err := m.probe(ctx, ID)
 			if err != nil {
 				logger.Errorf(ctx, err, "get workload %v status failed", ID)
 				return
 			}
+			workloadStatus = m.states.checked(workloadStatus, m.config.HealthCheck)
 
 			if workloadStatus.Running {
 				logger.Debugf(ctx, "workload %s is running", workloadStatus.ID)
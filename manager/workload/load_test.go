@@ -40,4 +40,23 @@ func TestLoad(t *testing.T) {
 	time.Sleep(2 * time.Second)
 	assert.Nil(t, err)
 	assertInitStatus(t, store)
+
+	states := manager.GetWorkloadStates()
+	assert.Len(t, states, 3)
+	for _, state := range states {
+		assert.Equal(t, state.ID, state.Status.ID)
+		assert.False(t, state.CheckedAt.IsZero())
+		assert.False(t, state.ReportedAt.IsZero())
+	}
+	// the logs of mock workloads end immediately
+	state, ok := manager.GetWorkloadState("Rei")
+	assert.True(t, ok)
+	assert.False(t, state.Attached)
+	assert.False(t, state.AttachedAt.IsZero())
+	assert.False(t, state.DetachedAt.IsZero())
+	state, ok = manager.GetWorkloadState("Asuka")
+	assert.True(t, ok)
+	assert.True(t, state.AttachedAt.IsZero())
+	_, ok = manager.GetWorkloadState("Kaworu")
+	assert.False(t, ok)
 }
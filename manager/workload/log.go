@@ -1,24 +1,35 @@
 package workload
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
-	"fmt"
 	"sync"
+	"time"
 
 	"github.com/alphadose/haxmap"
+	"github.com/projecteru2/agent/common"
 	"github.com/projecteru2/agent/types"
 	"github.com/projecteru2/agent/utils"
 	corelog "github.com/projecteru2/core/log"
 	coreutils "github.com/projecteru2/core/utils"
 )
 
+const (
+	defaultBroadcastBuffer    = 10000
+	defaultBroadcastQueueSize = 1000
+)
+
 type subscriber struct {
+	ID      string
+	app     string
+	query   *types.LogQuery
 	ctx     context.Context
 	cancel  context.CancelFunc
-	buf     *bufio.ReadWriter
+	stream  LogStream
+	queue   chan []byte
 	errChan chan error
+	// done is closed when run returns, the stream is not written after that
+	done chan struct{}
 }
 
 func (s *subscriber) isDone() bool {
@@ -30,17 +41,176 @@ func (s *subscriber) isDone() bool {
 	}
 }
 
-// logBroadcaster receives log and broadcasts to subscribers
+// fail stops the subscriber, the error is returned to PullLog
+func (s *subscriber) fail(err error) {
+	select {
+	case s.errChan <- err:
+	default:
+	}
+	s.cancel()
+}
+
+// run writes the replayed logs, then the queued ones, until the subscriber is done
+func (s *subscriber) run(backlog [][]byte) {
+	defer close(s.done)
+	for _, data := range backlog {
+		if s.isDone() {
+			return
+		}
+		if err := s.stream.Write(data); err != nil {
+			s.fail(err)
+			return
+		}
+	}
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case data := <-s.queue:
+			// select picks randomly if both are ready, don't write after it's done
+			if s.isDone() {
+				return
+			}
+			if err := s.stream.Write(data); err != nil {
+				corelog.Debugf(nil, "[broadcast] failed to write into %v, err: %v", s.ID, err) //nolint
+				s.fail(err)
+				return
+			}
+		}
+	}
+}
+
+// logHistory is a ring buffer of the recent logs of an app, limited by lines and bytes of data
+type logHistory struct {
+	sync.Mutex
+	config types.LogHistoryConfig
+	logs   []*types.Log
+	bytes  int
+	// workloads are the IDs of workloads having logs in history
+	workloads map[string]bool
+}
+
+func newLogHistory(config types.LogHistoryConfig) *logHistory {
+	return &logHistory{config: config, workloads: map[string]bool{}}
+}
+
+func (h *logHistory) add(log *types.Log) {
+	h.Lock()
+	defer h.Unlock()
+	h.workloads[log.ID] = true
+	h.logs = append(h.logs, log)
+	h.bytes += len(log.Data)
+	for len(h.logs) > 0 && ((h.config.Lines > 0 && len(h.logs) > h.config.Lines) || (h.config.Bytes > 0 && h.bytes > h.config.Bytes)) {
+		h.bytes -= len(h.logs[0].Data)
+		h.logs[0] = nil
+		h.logs = h.logs[1:]
+	}
+}
+
+// get returns the last tail logs since the time, tail 0 means all of them
+func (h *logHistory) get(tail int, since time.Time) []*types.Log {
+	h.Lock()
+	defer h.Unlock()
+	start := 0
+	if !since.IsZero() {
+		start = len(h.logs)
+		for start > 0 && !logTime(h.logs[start-1]).Before(since) {
+			start--
+		}
+	}
+	if tail > 0 && len(h.logs)-start > tail {
+		start = len(h.logs) - tail
+	}
+	logs := make([]*types.Log, len(h.logs)-start)
+	copy(logs, h.logs[start:])
+	return logs
+}
+
+// retain forgets the workloads not in IDs, returns false if no workload is left
+func (h *logHistory) retain(IDs map[string]bool) bool {
+	h.Lock()
+	defer h.Unlock()
+	for ID := range h.workloads {
+		if !IDs[ID] {
+			delete(h.workloads, ID)
+		}
+	}
+	return len(h.workloads) > 0
+}
+
+func logTime(log *types.Log) time.Time {
+	t, err := time.ParseInLocation(common.DateTimeFormat, log.Datetime, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// logBroadcaster receives log and broadcasts to subscribers.
+// Each subscriber has its own bounded queue, so a slow subscriber won't block the others,
+// it loses logs or gets evicted when its queue is full.
 type logBroadcaster struct {
 	sync.RWMutex
 	logC           chan *types.Log
 	subscribersMap *haxmap.Map[string, map[string]*subscriber] // format: map[app string, map[ID string]*subscriber]
+	historyConfig  types.LogHistoryConfig
+	histories      *haxmap.Map[string, *logHistory]
+	queueSize      int
+	policy         string
 }
 
-func newLogBroadcaster() *logBroadcaster {
+func newLogBroadcaster(config *types.LogConfig) *logBroadcaster {
+	buffer := config.Broadcast.Buffer
+	if buffer <= 0 {
+		buffer = defaultBroadcastBuffer
+	}
+	queueSize := config.Broadcast.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultBroadcastQueueSize
+	}
 	return &logBroadcaster{
-		logC:           make(chan *types.Log),
+		logC:           make(chan *types.Log, buffer),
 		subscribersMap: haxmap.New[string, map[string]*subscriber](),
+		historyConfig:  config.History,
+		histories:      haxmap.New[string, *logHistory](),
+		queueSize:      queueSize,
+		policy:         config.Broadcast.Policy,
+	}
+}
+
+// publish sends the log to broadcaster without blocking, the log is dropped if the buffer is full
+func (l *logBroadcaster) publish(log *types.Log) {
+	select {
+	case l.logC <- log:
+	default:
+		broadcastDroppedLines.Inc()
+	}
+}
+
+func (l *logBroadcaster) getHistory(app string) *logHistory {
+	history, _ := l.histories.GetOrCompute(app, func() *logHistory {
+		return newLogHistory(l.historyConfig)
+	})
+	return history
+}
+
+// retainHistories removes the histories of apps whose workloads are all removed
+func (l *logBroadcaster) retainHistories(workloadIDs []string) {
+	l.Lock()
+	defer l.Unlock()
+	IDs := map[string]bool{}
+	for _, ID := range workloadIDs {
+		IDs[ID] = true
+	}
+	var apps []string
+	l.histories.ForEach(func(app string, history *logHistory) bool {
+		if !history.retain(IDs) {
+			apps = append(apps, app)
+		}
+		return true
+	})
+	for _, app := range apps {
+		l.histories.Del(app)
 	}
 }
 
@@ -58,27 +228,54 @@ func (l *logBroadcaster) deleteSubscribers(app string) {
 }
 
 // subscribe subscribes logs of the specific app.
-func (l *logBroadcaster) subscribe(ctx context.Context, app string, buf *bufio.ReadWriter) (string, chan error, func()) {
+// The recent logs picked by query are replayed before the live ones,
+// they are picked with lock held, so no log is missed or sent twice at the handoff.
+func (l *logBroadcaster) subscribe(ctx context.Context, query *types.LogQuery, stream LogStream) (string, chan error, func(), error) {
 	l.Lock()
 	defer l.Unlock()
 
-	subscribers := l.getSubscribers(app)
+	app := query.App
+	var backlog [][]byte
+	if query.Tail > 0 || !query.Since.IsZero() {
+		for _, log := range l.getHistory(app).get(query.Tail, query.Since) {
+			if !query.Match(log) {
+				continue
+			}
+			data, err := json.Marshal(log)
+			if err != nil {
+				return "", nil, nil, err
+			}
+			backlog = append(backlog, data)
+		}
+	}
+
 	ID := coreutils.RandomString(8)
 	ctx, cancel := context.WithCancel(ctx)
-	errChan := make(chan error)
-
-	subscribers[ID] = &subscriber{
+	sub := &subscriber{
+		ID:      ID,
+		app:     app,
+		query:   query,
 		ctx:     ctx,
 		cancel:  cancel,
-		buf:     buf,
-		errChan: errChan,
+		stream:  stream,
+		queue:   make(chan []byte, l.queueSize),
+		errChan: make(chan error, 1),
+		done:    make(chan struct{}),
 	}
+	// registered after run is submitted, so a subscriber is never left without a consumer
+	if err := utils.Pool.Submit(func() { sub.run(backlog) }); err != nil {
+		cancel()
+		return "", nil, nil, err
+	}
+	l.getSubscribers(app)[ID] = sub
 
 	corelog.Infof(ctx, "%s %s log subscribed", app, ID)
-	return ID, errChan, func() {
+	// unsubscribing waits for run to return, so the stream is not written after its handler returns
+	return ID, sub.errChan, func() {
 		cancel()
-		_ = utils.Pool.Submit(func() { l.unsubscribe(app, ID) })
-	}
+		l.unsubscribe(app, ID)
+		<-sub.done
+	}, nil
 }
 
 func (l *logBroadcaster) unsubscribe(app string, ID string) {
@@ -86,10 +283,6 @@ func (l *logBroadcaster) unsubscribe(app string, ID string) {
 	defer l.Unlock()
 
 	subscribers := l.getSubscribers(app)
-	subscriber, ok := subscribers[ID]
-	if ok {
-		close(subscriber.errChan)
-	}
 	delete(subscribers, ID)
 
 	corelog.Infof(nil, "%s %s detached", app, ID) //nolint
@@ -100,42 +293,46 @@ func (l *logBroadcaster) unsubscribe(app string, ID string) {
 	}
 }
 
+// broadcast queues the log for subscribers without blocking,
+// if the queue of a subscriber is full, the log is dropped, or the subscriber is evicted
 func (l *logBroadcaster) broadcast(log *types.Log) {
 	l.RLock()
 	defer l.RUnlock()
 
+	if l.historyConfig.Lines > 0 || l.historyConfig.Bytes > 0 {
+		l.getHistory(log.Name).add(log)
+	}
+
 	subscribers := l.getSubscribers(log.Name)
 	if len(subscribers) == 0 {
 		return
 	}
-	data, err := json.Marshal(log)
-	if err != nil {
-		corelog.Error(nil, err) //nolint
-		return
-	}
-	line := fmt.Sprintf("%X\r\n%s\r\n\r\n", len(data)+2, string(data))
-
-	// use wait group to make sure the logs are ordered
-	wg := &sync.WaitGroup{}
-	wg.Add(len(subscribers))
+	var data []byte
 	for ID, sub := range subscribers {
-		ID := ID
-		sub := sub
-		_ = utils.Pool.Submit(func() {
-			defer wg.Done()
-			if sub.isDone() {
+		// filter before encoding, so filtered subscribers cost almost nothing
+		if sub.isDone() || !sub.query.Match(log) {
+			continue
+		}
+		if data == nil {
+			var err error
+			if data, err = json.Marshal(log); err != nil {
+				corelog.Error(nil, err) //nolint
 				return
 			}
-			if _, err := sub.buf.Write([]byte(line)); err != nil {
-				corelog.Debugf(nil, "[broadcast] failed to write into %v, err: %v", ID, err) //nolint
-				sub.cancel()
-				sub.errChan <- err
-				return
+		}
+		select {
+		case sub.queue <- data:
+			subscriberQueueLength.WithLabelValues(sub.app).Observe(float64(len(sub.queue)))
+		default:
+			if l.policy == common.BroadcastPolicyEvict {
+				corelog.Warnf(nil, "[broadcast] subscriber %s of %s is too slow, evicted", ID, sub.app) //nolint
+				evictedSubscribers.WithLabelValues(sub.app).Inc()
+				sub.fail(common.ErrSubscriberEvicted)
+				continue
 			}
-			sub.buf.Flush()
-		})
+			subscriberDroppedLines.WithLabelValues(sub.app).Inc()
+		}
 	}
-	wg.Wait()
 }
 
 func (l *logBroadcaster) run(ctx context.Context) {
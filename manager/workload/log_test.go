@@ -3,10 +3,14 @@ package workload
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/projecteru2/agent/common"
 	"github.com/projecteru2/agent/types"
 
 	"github.com/bmizerany/pat"
@@ -33,7 +37,7 @@ func TestLogBroadcaster(t *testing.T) {
 				return
 			}
 			defer conn.Close()
-			manager.PullLog(logCtx, app, buf)
+			manager.PullLog(logCtx, &types.LogQuery{App: app}, NewChunkedLogStream(buf))
 		}
 	}
 	server := &http.Server{Addr: ":12310"}
@@ -106,3 +110,188 @@ func TestLogBroadcaster(t *testing.T) {
 	})
 	assert.Equal(t, count, 0)
 }
+
+func TestLogHistory(t *testing.T) {
+	h := newLogHistory(types.LogHistoryConfig{Lines: 3, Bytes: 10})
+	now := time.Now().Truncate(time.Second)
+	for i, data := range []string{"0", "1", "2", "3"} {
+		h.add(&types.Log{Data: data, Datetime: now.Add(time.Duration(i) * time.Second).Format(common.DateTimeFormat)})
+	}
+	// limited by lines
+	assert.Len(t, h.get(0, time.Time{}), 3)
+	assert.Equal(t, "3", h.get(1, time.Time{})[0].Data)
+	logs := h.get(0, now.Add(2*time.Second))
+	assert.Len(t, logs, 2)
+	assert.Equal(t, "2", logs[0].Data)
+	assert.Equal(t, "3", h.get(1, now.Add(2*time.Second))[0].Data)
+	assert.Empty(t, h.get(0, now.Add(time.Minute)))
+
+	// limited by bytes
+	h.add(&types.Log{Data: "0123456789"})
+	logs = h.get(10, time.Time{})
+	assert.Len(t, logs, 1)
+	assert.Equal(t, 10, h.bytes)
+}
+
+func TestRetainHistories(t *testing.T) {
+	l := newLogBroadcaster(&types.LogConfig{History: types.LogHistoryConfig{Lines: 10}})
+	l.broadcast(&types.Log{ID: "Rei", Name: "nerv", Data: "0"})
+	l.broadcast(&types.Log{ID: "Shinji", Name: "nerv", Data: "1"})
+	l.broadcast(&types.Log{ID: "Kaworu", Name: "seele", Data: "2"})
+
+	// kept while one of the workloads of the app is alive
+	l.retainHistories([]string{"Rei", "Asuka"})
+	_, ok := l.histories.Get("nerv")
+	assert.True(t, ok)
+	_, ok = l.histories.Get("seele")
+	assert.False(t, ok)
+
+	l.retainHistories([]string{"Asuka"})
+	assert.Equal(t, uintptr(0), l.histories.Len())
+}
+
+// testStream collects the data of logs, blocks writing while block is not closed
+type testStream struct {
+	sync.Mutex
+	data  []string
+	block chan struct{}
+}
+
+func (s *testStream) Write(data []byte) error {
+	if s.block != nil {
+		<-s.block
+	}
+	log := &types.Log{}
+	if err := json.Unmarshal(data, log); err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.data = append(s.data, log.Type+":"+log.Data)
+	return nil
+}
+
+func (s *testStream) Heartbeat() error {
+	return nil
+}
+
+func (s *testStream) get() []string {
+	s.Lock()
+	defer s.Unlock()
+	return append([]string{}, s.data...)
+}
+
+func TestLogBroadcasterReplay(t *testing.T) {
+	l := newLogBroadcaster(&types.LogConfig{History: types.LogHistoryConfig{Lines: 10}})
+	for _, data := range []string{"data0", "data1", "data2"} {
+		l.broadcast(&types.Log{Name: "nerv", Data: data, Datetime: time.Now().Format(common.DateTimeFormat)})
+	}
+
+	stream := &testStream{}
+	_, _, unsubscribe, err := l.subscribe(context.Background(), &types.LogQuery{App: "nerv", Tail: 2}, stream)
+	assert.NoError(t, err)
+	defer unsubscribe()
+	l.broadcast(&types.Log{Name: "nerv", Data: "data3"})
+
+	// the last 2 logs are replayed, then the live one
+	expected := []string{":data1", ":data2", ":data3"}
+	assert.Eventually(t, func() bool { return assert.ObjectsAreEqual(expected, stream.get()) }, time.Second, 10*time.Millisecond)
+}
+
+func TestLogBroadcasterUnsubscribe(t *testing.T) {
+	l := newLogBroadcaster(&types.LogConfig{History: types.LogHistoryConfig{Lines: 10}})
+	for _, data := range []string{"data0", "data1", "data2"} {
+		l.broadcast(&types.Log{Name: "nerv", Data: data, Datetime: time.Now().Format(common.DateTimeFormat)})
+	}
+
+	stream := &testStream{block: make(chan struct{})}
+	_, _, unsubscribe, err := l.subscribe(context.Background(), &types.LogQuery{App: "nerv", Tail: 3}, stream)
+	assert.NoError(t, err)
+	// wait for the writer to be blocked by the first replayed log
+	time.Sleep(100 * time.Millisecond)
+	unsubscribed := make(chan struct{})
+	go func() {
+		unsubscribe()
+		close(unsubscribed)
+	}()
+	// unsubscribing waits for the writer
+	select {
+	case <-unsubscribed:
+		t.Fatal("unsubscribed while writing")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(stream.block)
+	select {
+	case <-unsubscribed:
+	case <-time.After(time.Second):
+		t.Fatal("unsubscribe is blocked")
+	}
+
+	// the replay stops after unsubscribing, and the live logs are not written
+	l.broadcast(&types.Log{Name: "nerv", Data: "data3"})
+	assert.Equal(t, []string{":data0"}, stream.get())
+	assert.Empty(t, l.getSubscribers("nerv"))
+}
+
+func TestLogBroadcasterFilter(t *testing.T) {
+	l := newLogBroadcaster(&types.LogConfig{History: types.LogHistoryConfig{Lines: 10}})
+	l.broadcast(&types.Log{Name: "nerv", Type: "stderr", Data: "old", Datetime: time.Now().Format(common.DateTimeFormat)})
+	l.broadcast(&types.Log{Name: "nerv", Type: "stdout", Data: "old", Datetime: time.Now().Format(common.DateTimeFormat)})
+
+	stream := &testStream{}
+	_, _, unsubscribe, err := l.subscribe(context.Background(), &types.LogQuery{App: "nerv", Tail: 10, Type: "stderr"}, stream)
+	assert.NoError(t, err)
+	defer unsubscribe()
+	l.broadcast(&types.Log{Name: "nerv", Type: "stdout", Data: "new"})
+	l.broadcast(&types.Log{Name: "nerv", Type: "stderr", Data: "new"})
+
+	expected := []string{"stderr:old", "stderr:new"}
+	assert.Eventually(t, func() bool { return assert.ObjectsAreEqual(expected, stream.get()) }, time.Second, 10*time.Millisecond)
+}
+
+func TestLogBroadcasterSlowSubscriber(t *testing.T) {
+	// drop policy: the slow subscriber loses logs, the fast one gets all of them
+	l := newLogBroadcaster(&types.LogConfig{Broadcast: types.LogBroadcastConfig{QueueSize: 2, Policy: common.BroadcastPolicyDrop}})
+	slow := &testStream{block: make(chan struct{})}
+	slowID, slowErrChan, unsubscribeSlow, err := l.subscribe(context.Background(), &types.LogQuery{App: "nerv"}, slow)
+	assert.NoError(t, err)
+	defer unsubscribeSlow()
+	slowQueue := l.getSubscribers("nerv")[slowID].queue
+	fast := &testStream{}
+	_, _, unsubscribeFast, err := l.subscribe(context.Background(), &types.LogQuery{App: "nerv"}, fast)
+	assert.NoError(t, err)
+	defer unsubscribeFast()
+
+	for i := 0; i < 10; i++ {
+		l.broadcast(&types.Log{Name: "nerv", Data: strconv.Itoa(i)})
+		// let the fast one keep up with broadcasting
+		assert.Eventually(t, func() bool { return len(fast.get()) == i+1 }, time.Second, time.Millisecond)
+		if i == 0 {
+			// wait for the writer of the slow one to be blocked by the first log
+			assert.Eventually(t, func() bool { return len(slowQueue) == 0 }, time.Second, time.Millisecond)
+		}
+	}
+	close(slow.block)
+	// the first one is taken by the writer, 2 are queued, the others are dropped
+	assert.Eventually(t, func() bool { return len(slow.get()) == 3 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{":0", ":1", ":2"}, slow.get())
+	assert.Empty(t, slowErrChan)
+
+	// evict policy: the slow subscriber is evicted
+	l = newLogBroadcaster(&types.LogConfig{Broadcast: types.LogBroadcastConfig{QueueSize: 2, Policy: common.BroadcastPolicyEvict}})
+	slow = &testStream{block: make(chan struct{})}
+	_, slowErrChan, unsubscribeSlow, err = l.subscribe(context.Background(), &types.LogQuery{App: "nerv"}, slow)
+	assert.NoError(t, err)
+	defer unsubscribeSlow()
+	// unsubscribing waits for the blocked writer
+	defer close(slow.block)
+	for i := 0; i < 10; i++ {
+		l.broadcast(&types.Log{Name: "nerv", Data: strconv.Itoa(i)})
+	}
+	select {
+	case err := <-slowErrChan:
+		assert.ErrorIs(t, err, common.ErrSubscriberEvicted)
+	case <-time.After(time.Second):
+		t.Fatal("slow subscriber is not evicted")
+	}
+}
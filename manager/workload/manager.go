@@ -1,13 +1,15 @@
 package workload
 
 import (
-	"bufio"
 	"context"
+	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
 
 	"github.com/alphadose/haxmap"
 	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/logs"
 	"github.com/projecteru2/agent/runtime"
 	"github.com/projecteru2/agent/runtime/docker"
 	runtimemocks "github.com/projecteru2/agent/runtime/mocks"
@@ -28,12 +30,22 @@ type Manager struct {
 	runtimeClient runtime.Runtime
 
 	nodeIP   string
-	forwards *utils.HashBackends
+	router   *logs.Router
+	redactor *logs.Redactor
 
 	checkWorkloadMutex *sync.Mutex
 	startingWorkloads  *haxmap.Map[string, *utils.RetryTask]
+	// probes limits the health checks running at the same time
+	probes chan struct{}
+
+	// ctx is the context of manager, the logs attached by API requests are detached when it's done
+	ctx context.Context
+
+	// loaded is set after the workloads are loaded when starting
+	loaded atomic.Bool
 
 	logBroadcaster *logBroadcaster
+	states         *workloadStates
 
 	// storeIdentifier indicates which eru this agent belongs to
 	// it can be used to identify the corresponding core
@@ -43,7 +55,7 @@ type Manager struct {
 
 // NewManager returns a workload manager
 func NewManager(ctx context.Context, config *types.Config) (*Manager, error) {
-	m := &Manager{config: config}
+	m := &Manager{config: config, ctx: ctx}
 
 	switch config.Store {
 	case common.GRPCStore:
@@ -89,12 +101,39 @@ func NewManager(ctx context.Context, config *types.Config) (*Manager, error) {
 		return nil, common.ErrInvalidRuntimeType
 	}
 
-	m.logBroadcaster = newLogBroadcaster()
-	m.forwards = utils.NewHashBackends(config.Log.Forwards)
+	if m.router, err = logs.NewRouter(&config.Log); err != nil {
+		log.WithFunc("NewManager").Error(ctx, err, "invalid log routes")
+		return nil, err
+	}
+	for name, rule := range config.Log.Multiline {
+		if _, err := logs.NewMerger(rule, nil); err != nil {
+			log.WithFunc("NewManager").Errorf(ctx, err, "invalid multiline rule %s", name)
+			return nil, err
+		}
+	}
+
+	if m.redactor, err = logs.NewRedactor(config.Log.Redaction); err != nil {
+		log.WithFunc("NewManager").Error(ctx, err, "invalid redaction rules")
+		return nil, err
+	}
+
+	switch config.Log.Broadcast.Policy {
+	case "", common.BroadcastPolicyDrop, common.BroadcastPolicyEvict:
+	default:
+		log.WithFunc("NewManager").Errorf(ctx, common.ErrInvalidBroadcastPolicy, "invalid broadcast policy %s", config.Log.Broadcast.Policy)
+		return nil, common.ErrInvalidBroadcastPolicy
+	}
+	m.logBroadcaster = newLogBroadcaster(&config.Log)
 	m.storeIdentifier = m.store.GetIdentifier(ctx)
 	m.nodeIP = nodeIP
 	m.checkWorkloadMutex = &sync.Mutex{}
 	m.startingWorkloads = haxmap.New[string, *utils.RetryTask]()
+	m.states = newWorkloadStates()
+	maxInFlight := config.HealthCheck.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = defaultMaxInFlight
+	}
+	m.probes = make(chan struct{}, maxInFlight)
 
 	return m, nil
 }
@@ -106,10 +145,16 @@ func (m *Manager) Run(ctx context.Context) error {
 	// start log broadcaster
 	_ = utils.Pool.Submit(func() { m.logBroadcaster.run(ctx) })
 
+	// reload redaction rules when modified
+	if m.redactor != nil {
+		_ = utils.Pool.Submit(func() { m.redactor.Watch(ctx) })
+	}
+
 	// initWorkloadStatus container
 	if err := m.initWorkloadStatus(ctx); err != nil {
 		return err
 	}
+	m.loaded.Store(true)
 
 	// start status watcher
 	_ = utils.Pool.Submit(func() { m.monitor(ctx) })
@@ -124,8 +169,12 @@ func (m *Manager) Run(ctx context.Context) error {
 }
 
 // PullLog pull logs for specific app
-func (m *Manager) PullLog(ctx context.Context, app string, buf *bufio.ReadWriter) {
-	ID, errChan, unsubscribe := m.logBroadcaster.subscribe(ctx, app, buf)
+func (m *Manager) PullLog(ctx context.Context, query *types.LogQuery, stream LogStream) {
+	ID, errChan, unsubscribe, err := m.logBroadcaster.subscribe(ctx, query, stream)
+	if err != nil {
+		log.WithFunc("PullLog").Error(ctx, err, "failed to replay log")
+		return
+	}
 	defer unsubscribe()
 
 	for {
@@ -140,3 +189,75 @@ func (m *Manager) PullLog(ctx context.Context, app string, buf *bufio.ReadWriter
 		}
 	}
 }
+
+// ListWorkloads lists the statuses of workloads on this node,
+// their health is not checked, see GetWorkloadStatus
+func (m *Manager) ListWorkloads(ctx context.Context) ([]*types.WorkloadStatus, error) {
+	logger := log.WithFunc("ListWorkloads")
+	workloadIDs, err := m.runtimeClient.ListWorkloadIDs(ctx, m.getBaseFilter())
+	if err != nil {
+		logger.Error(ctx, err, "failed to list workloads")
+		return nil, err
+	}
+
+	mutex := &sync.Mutex{}
+	statuses := []*types.WorkloadStatus{}
+	wg := &sync.WaitGroup{}
+	for _, workloadID := range workloadIDs {
+		wg.Add(1)
+		ID := workloadID
+		_ = utils.Pool.Submit(func() {
+			defer wg.Done()
+			status, err := m.runtimeClient.GetStatus(ctx, ID, false)
+			if err != nil {
+				// the workload may be removed after listing
+				logger.Errorf(ctx, err, "get workload %v status failed", ID)
+				return
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			statuses = append(statuses, status)
+		})
+	}
+	wg.Wait()
+	return statuses, nil
+}
+
+// GetWorkloadStatus returns the status of the workload with health checked
+func (m *Manager) GetWorkloadStatus(ctx context.Context, ID string) (*types.WorkloadStatus, error) {
+	return m.probe(ctx, ID)
+}
+
+// Ready returns nil if the manager is ready:
+// the workloads are loaded, and both runtime daemon and core are reachable
+func (m *Manager) Ready(ctx context.Context) error {
+	if !m.loaded.Load() {
+		return common.ErrWorkloadsNotLoaded
+	}
+	var err error
+	utils.WithTimeout(ctx, m.config.GlobalConnectionTimeout, func(ctx context.Context) {
+		if !m.runtimeClient.IsDaemonRunning(ctx) {
+			err = common.ErrRuntimeUnreachable
+			return
+		}
+		if _, e := m.store.GetNode(ctx, m.config.HostName); e != nil {
+			err = fmt.Errorf("%w: %v", common.ErrCoreUnreachable, e)
+		}
+	})
+	return err
+}
+
+// GetWorkloadStates returns the recorded states of all workloads, sorted by ID
+func (m *Manager) GetWorkloadStates() []*types.WorkloadState {
+	return m.states.list()
+}
+
+// GetWorkloadState returns the recorded state of the workload, false if agent knows nothing about it
+func (m *Manager) GetWorkloadState(ID string) (*types.WorkloadState, bool) {
+	return m.states.get(ID)
+}
+
+// WatchEvents re-emits the workload events seen by monitor, until ctx is done
+func (m *Manager) WatchEvents(ctx context.Context) <-chan *types.WorkloadEventMessage {
+	return eventHandler.Subscribe(ctx)
+}
@@ -0,0 +1,43 @@
+package workload
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	broadcastDroppedLines = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "log_broadcast_dropped_lines_total",
+		Help: "lines dropped because the log broadcast buffer is full.",
+	})
+	subscriberQueueLength = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "log_subscriber_queue_length",
+		Help:    "lines waiting in the queue of log subscribers, observed when a line is queued.",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 6),
+	}, []string{"app"})
+	subscriberDroppedLines = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "log_subscriber_dropped_lines_total",
+		Help: "lines dropped because the queue of a log subscriber is full.",
+	}, []string{"app"})
+	evictedSubscribers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "log_subscribers_evicted_total",
+		Help: "log subscribers evicted because they are too slow.",
+	}, []string{"app"})
+	probeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "health_check_duration_seconds",
+		Help:    "latency of health checks of workloads.",
+		Buckets: []float64{0.005, 0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"result"})
+	probesInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "health_checks_in_flight",
+		Help: "health checks running now.",
+	})
+)
+
+func init() { //nolint:gochecknoinits
+	prometheus.MustRegister(
+		broadcastDroppedLines,
+		subscriberQueueLength,
+		subscriberDroppedLines,
+		evictedSubscribers,
+		probeDuration,
+		probesInFlight,
+	)
+}
@@ -73,18 +73,20 @@ func (m *Manager) checkOneWorkloadWithBackoffRetry(ctx context.Context, ID strin
 func (m *Manager) handleWorkloadStart(ctx context.Context, event *types.WorkloadEventMessage) {
 	logger := log.WithFunc("handleWorkloadStart").WithField("ID", event.ID)
 	logger.Debug(ctx, "workload start")
-	workloadStatus, err := m.runtimeClient.GetStatus(ctx, event.ID, true)
+	m.states.started(event.ID)
+	workloadStatus, err := m.probe(ctx, event.ID)
 	if err != nil {
 		logger.Error(ctx, err, "faild to get workload status")
 		return
 	}
+	workloadStatus = m.states.checked(workloadStatus, m.config.HealthCheck)
 
 	if workloadStatus.Running {
 		_ = utils.Pool.Submit(func() { m.attach(ctx, event.ID) })
 	}
 
 	if workloadStatus.Healthy {
-		if err := m.store.SetWorkloadStatus(ctx, workloadStatus, m.config.GetHealthCheckStatusTTL()); err != nil {
+		if err := m.reportWorkloadStatus(ctx, workloadStatus); err != nil {
 			logger.Error(ctx, err, "update deploy status failed")
 		}
 	} else {
@@ -95,13 +97,14 @@ func (m *Manager) handleWorkloadStart(ctx context.Context, event *types.Workload
 func (m *Manager) handleWorkloadDie(ctx context.Context, event *types.WorkloadEventMessage) {
 	logger := log.WithFunc("handleWorkloadDie").WithField("ID", event.ID)
 	logger.Debug(ctx, "container die")
-	workloadStatus, err := m.runtimeClient.GetStatus(ctx, event.ID, true)
+	workloadStatus, err := m.probe(ctx, event.ID)
 	if err != nil {
 		logger.Error(ctx, err, "faild to get workload status")
 		return
 	}
+	workloadStatus = m.states.checked(workloadStatus, m.config.HealthCheck)
 
-	if err := m.store.SetWorkloadStatus(ctx, workloadStatus, m.config.GetHealthCheckStatusTTL()); err != nil {
+	if err := m.reportWorkloadStatus(ctx, workloadStatus); err != nil {
 		logger.Error(ctx, err, "update deploy status failed")
 	}
 }
@@ -0,0 +1,162 @@
+package workload
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/projecteru2/agent/types"
+	coreutils "github.com/projecteru2/core/utils"
+)
+
+// workloadStates records the states of workloads, for inspecting what agent is managing
+type workloadStates struct {
+	sync.RWMutex
+	states map[string]*types.WorkloadState
+	// attaches counts the running attaches of workloads, including the ones tearing down
+	attaches map[string]int
+}
+
+func newWorkloadStates() *workloadStates {
+	return &workloadStates{
+		states:   map[string]*types.WorkloadState{},
+		attaches: map[string]int{},
+	}
+}
+
+func (s *workloadStates) update(ID string, f func(state *types.WorkloadState)) {
+	s.Lock()
+	defer s.Unlock()
+	state, ok := s.states[ID]
+	if !ok {
+		state = &types.WorkloadState{ID: ID}
+		s.states[ID] = state
+	}
+	f(state)
+}
+
+// checked records the status computed by health check, and returns the status to report.
+// The Healthy of a running workload changes only after the thresholds of consecutive results are crossed,
+// so a single failed or passed probe won't make it flap, unless it's the first probe.
+func (s *workloadStates) checked(status *types.WorkloadStatus, config types.HealthCheckConfig) *types.WorkloadStatus {
+	result := *status
+	s.update(status.ID, func(state *types.WorkloadState) {
+		switch {
+		case !status.Running:
+			state.Successes, state.Failures = 0, 0
+		case status.Healthy:
+			state.Successes++
+			state.Failures = 0
+		case time.Since(state.StartedAt) < config.StartPeriod:
+			// failures in start period are not counted
+			state.Successes = 0
+		default:
+			state.Failures++
+			state.Successes = 0
+		}
+		if status.Running && state.Status != nil {
+			result.Healthy = state.Status.Running && state.Status.Healthy
+			if !result.Healthy && state.Successes >= coreutils.Max(config.HealthyThreshold, 1) {
+				result.Healthy = true
+			}
+			if result.Healthy && state.Failures >= coreutils.Max(config.UnhealthyThreshold, 1) {
+				result.Healthy = false
+			}
+		}
+		state.Status = &result
+		state.CheckedAt = time.Now()
+	})
+	return &result
+}
+
+// started records the time of start event, from when the start period begins
+func (s *workloadStates) started(ID string) {
+	s.update(ID, func(state *types.WorkloadState) {
+		state.StartedAt = time.Now()
+		state.Successes, state.Failures = 0, 0
+	})
+}
+
+// reported records the result of reporting status to store
+func (s *workloadStates) reported(status *types.WorkloadStatus, err error) {
+	s.update(status.ID, func(state *types.WorkloadState) {
+		if err != nil {
+			state.ReportError = err.Error()
+			return
+		}
+		state.ReportedAt = time.Now()
+		state.ReportError = ""
+	})
+}
+
+// beginAttach records an attach of the workload, returns the func to call when the attach returns
+func (s *workloadStates) beginAttach(ID string) func() {
+	s.Lock()
+	defer s.Unlock()
+	s.attaches[ID]++
+	return func() {
+		s.Lock()
+		defer s.Unlock()
+		if s.attaches[ID]--; s.attaches[ID] <= 0 {
+			delete(s.attaches, ID)
+		}
+	}
+}
+
+// isAttaching returns true if the workload is being attached or attached
+func (s *workloadStates) isAttaching(ID string) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.attaches[ID] > 0
+}
+
+func (s *workloadStates) attached(ID string, attached bool) {
+	s.update(ID, func(state *types.WorkloadState) {
+		state.Attached = attached
+		if attached {
+			state.AttachedAt = time.Now()
+		} else {
+			state.DetachedAt = time.Now()
+		}
+	})
+}
+
+// retain removes the states of workloads not in IDs
+func (s *workloadStates) retain(IDs []string) {
+	s.Lock()
+	defer s.Unlock()
+	keep := map[string]bool{}
+	for _, ID := range IDs {
+		keep[ID] = true
+	}
+	for ID := range s.states {
+		if !keep[ID] {
+			delete(s.states, ID)
+		}
+	}
+}
+
+// get returns a copy of the state
+func (s *workloadStates) get(ID string) (*types.WorkloadState, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	state, ok := s.states[ID]
+	if !ok {
+		return nil, false
+	}
+	r := *state
+	return &r, true
+}
+
+// list returns copies of all states, sorted by ID
+func (s *workloadStates) list() []*types.WorkloadState {
+	s.RLock()
+	defer s.RUnlock()
+	states := make([]*types.WorkloadState, 0, len(s.states))
+	for _, state := range s.states {
+		r := *state
+		states = append(states, &r)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].ID < states[j].ID })
+	return states
+}
@@ -0,0 +1,41 @@
+package workload
+
+import (
+	"bufio"
+	"fmt"
+	"sync"
+)
+
+// LogStream is the transport of a log subscriber
+type LogStream interface {
+	// Write sends a JSON encoded log
+	Write(data []byte) error
+	// Heartbeat keeps the stream alive, and detects dead subscribers
+	Heartbeat() error
+}
+
+// ChunkedLogStream writes logs as HTTP chunks into a hijacked connection
+type ChunkedLogStream struct {
+	sync.Mutex
+	buf *bufio.ReadWriter
+}
+
+// NewChunkedLogStream .
+func NewChunkedLogStream(buf *bufio.ReadWriter) *ChunkedLogStream {
+	return &ChunkedLogStream{buf: buf}
+}
+
+// Write .
+func (s *ChunkedLogStream) Write(data []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, err := fmt.Fprintf(s.buf, "%X\r\n%s\r\n\r\n", len(data)+2, data); err != nil {
+		return err
+	}
+	return s.buf.Flush()
+}
+
+// Heartbeat does nothing, an empty chunk means the end of stream
+func (s *ChunkedLogStream) Heartbeat() error {
+	return nil
+}
@@ -0,0 +1,275 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: rpc/gen/agent.proto
+
+// not pb, full names of messages must not conflict with the ones of core
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	AgentRPC_StreamLogs_FullMethodName        = "/agent.AgentRPC/StreamLogs"
+	AgentRPC_ListWorkloads_FullMethodName     = "/agent.AgentRPC/ListWorkloads"
+	AgentRPC_GetWorkloadStatus_FullMethodName = "/agent.AgentRPC/GetWorkloadStatus"
+	AgentRPC_WatchEvents_FullMethodName       = "/agent.AgentRPC/WatchEvents"
+)
+
+// AgentRPCClient is the client API for AgentRPC service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AgentRPCClient interface {
+	StreamLogs(ctx context.Context, in *StreamLogsOptions, opts ...grpc.CallOption) (AgentRPC_StreamLogsClient, error)
+	ListWorkloads(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*WorkloadStatuses, error)
+	GetWorkloadStatus(ctx context.Context, in *GetWorkloadStatusOptions, opts ...grpc.CallOption) (*WorkloadStatus, error)
+	WatchEvents(ctx context.Context, in *Empty, opts ...grpc.CallOption) (AgentRPC_WatchEventsClient, error)
+}
+
+type agentRPCClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAgentRPCClient(cc grpc.ClientConnInterface) AgentRPCClient {
+	return &agentRPCClient{cc}
+}
+
+func (c *agentRPCClient) StreamLogs(ctx context.Context, in *StreamLogsOptions, opts ...grpc.CallOption) (AgentRPC_StreamLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AgentRPC_ServiceDesc.Streams[0], AgentRPC_StreamLogs_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentRPCStreamLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AgentRPC_StreamLogsClient interface {
+	Recv() (*Log, error)
+	grpc.ClientStream
+}
+
+type agentRPCStreamLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentRPCStreamLogsClient) Recv() (*Log, error) {
+	m := new(Log)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *agentRPCClient) ListWorkloads(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*WorkloadStatuses, error) {
+	out := new(WorkloadStatuses)
+	err := c.cc.Invoke(ctx, AgentRPC_ListWorkloads_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentRPCClient) GetWorkloadStatus(ctx context.Context, in *GetWorkloadStatusOptions, opts ...grpc.CallOption) (*WorkloadStatus, error) {
+	out := new(WorkloadStatus)
+	err := c.cc.Invoke(ctx, AgentRPC_GetWorkloadStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentRPCClient) WatchEvents(ctx context.Context, in *Empty, opts ...grpc.CallOption) (AgentRPC_WatchEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AgentRPC_ServiceDesc.Streams[1], AgentRPC_WatchEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentRPCWatchEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AgentRPC_WatchEventsClient interface {
+	Recv() (*WorkloadEvent, error)
+	grpc.ClientStream
+}
+
+type agentRPCWatchEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentRPCWatchEventsClient) Recv() (*WorkloadEvent, error) {
+	m := new(WorkloadEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AgentRPCServer is the server API for AgentRPC service.
+// All implementations should embed UnimplementedAgentRPCServer
+// for forward compatibility
+type AgentRPCServer interface {
+	StreamLogs(*StreamLogsOptions, AgentRPC_StreamLogsServer) error
+	ListWorkloads(context.Context, *Empty) (*WorkloadStatuses, error)
+	GetWorkloadStatus(context.Context, *GetWorkloadStatusOptions) (*WorkloadStatus, error)
+	WatchEvents(*Empty, AgentRPC_WatchEventsServer) error
+}
+
+// UnimplementedAgentRPCServer should be embedded to have forward compatible implementations.
+type UnimplementedAgentRPCServer struct {
+}
+
+func (UnimplementedAgentRPCServer) StreamLogs(*StreamLogsOptions, AgentRPC_StreamLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamLogs not implemented")
+}
+func (UnimplementedAgentRPCServer) ListWorkloads(context.Context, *Empty) (*WorkloadStatuses, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWorkloads not implemented")
+}
+func (UnimplementedAgentRPCServer) GetWorkloadStatus(context.Context, *GetWorkloadStatusOptions) (*WorkloadStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWorkloadStatus not implemented")
+}
+func (UnimplementedAgentRPCServer) WatchEvents(*Empty, AgentRPC_WatchEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+
+// UnsafeAgentRPCServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AgentRPCServer will
+// result in compilation errors.
+type UnsafeAgentRPCServer interface {
+	mustEmbedUnimplementedAgentRPCServer()
+}
+
+func RegisterAgentRPCServer(s grpc.ServiceRegistrar, srv AgentRPCServer) {
+	s.RegisterService(&AgentRPC_ServiceDesc, srv)
+}
+
+func _AgentRPC_StreamLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamLogsOptions)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentRPCServer).StreamLogs(m, &agentRPCStreamLogsServer{stream})
+}
+
+type AgentRPC_StreamLogsServer interface {
+	Send(*Log) error
+	grpc.ServerStream
+}
+
+type agentRPCStreamLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentRPCStreamLogsServer) Send(m *Log) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _AgentRPC_ListWorkloads_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRPCServer).ListWorkloads(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRPC_ListWorkloads_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRPCServer).ListWorkloads(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentRPC_GetWorkloadStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWorkloadStatusOptions)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRPCServer).GetWorkloadStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRPC_GetWorkloadStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRPCServer).GetWorkloadStatus(ctx, req.(*GetWorkloadStatusOptions))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentRPC_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentRPCServer).WatchEvents(m, &agentRPCWatchEventsServer{stream})
+}
+
+type AgentRPC_WatchEventsServer interface {
+	Send(*WorkloadEvent) error
+	grpc.ServerStream
+}
+
+type agentRPCWatchEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentRPCWatchEventsServer) Send(m *WorkloadEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// AgentRPC_ServiceDesc is the grpc.ServiceDesc for AgentRPC service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AgentRPC_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "agent.AgentRPC",
+	HandlerType: (*AgentRPCServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListWorkloads",
+			Handler:    _AgentRPC_ListWorkloads_Handler,
+		},
+		{
+			MethodName: "GetWorkloadStatus",
+			Handler:    _AgentRPC_GetWorkloadStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamLogs",
+			Handler:       _AgentRPC_StreamLogs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _AgentRPC_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "rpc/gen/agent.proto",
+}
@@ -0,0 +1,230 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/projecteru2/agent/api"
+	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/manager/workload"
+	pb "github.com/projecteru2/agent/rpc/gen"
+	"github.com/projecteru2/agent/types"
+	"github.com/projecteru2/agent/utils"
+	"github.com/projecteru2/core/log"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+const defaultShutdownTimeout = 10 * time.Second
+
+// AgentRPC implements the gRPC service of agent
+type AgentRPC struct {
+	config           *types.Config
+	workloadsManager *workload.Manager
+	authenticator    *api.Authenticator
+	tls              *api.TLS
+	// stop is closed when stopping, to end the streams
+	stop chan struct{}
+}
+
+// New returns the gRPC service of agent, it shares the tls and credentials with HTTP API
+func New(config *types.Config, workloadsManager *workload.Manager, tls *api.TLS) *AgentRPC {
+	return &AgentRPC{
+		config:           config,
+		workloadsManager: workloadsManager,
+		authenticator:    api.NewAuthenticator(&config.API),
+		tls:              tls,
+		stop:             make(chan struct{}),
+	}
+}
+
+// StreamLogs streams logs of an app, the same as /log/ of HTTP API
+func (a *AgentRPC) StreamLogs(opts *pb.StreamLogsOptions, stream pb.AgentRPC_StreamLogsServer) error {
+	query, err := toLogQuery(opts)
+	if err != nil {
+		return grpcstatus.Error(codes.InvalidArgument, err.Error())
+	}
+	ctx, cancel := a.streamContext(stream.Context())
+	defer cancel()
+	a.workloadsManager.PullLog(ctx, query, &logStream{stream: stream})
+	return nil
+}
+
+// ListWorkloads lists the statuses of workloads on this node
+func (a *AgentRPC) ListWorkloads(ctx context.Context, _ *pb.Empty) (*pb.WorkloadStatuses, error) {
+	statuses, err := a.workloadsManager.ListWorkloads(ctx)
+	if err != nil {
+		return nil, grpcstatus.Error(codes.Internal, err.Error())
+	}
+	r := &pb.WorkloadStatuses{}
+	for _, status := range statuses {
+		r.Statuses = append(r.Statuses, toRPCWorkloadStatus(status))
+	}
+	return r, nil
+}
+
+// GetWorkloadStatus returns the status of a workload with health checked
+func (a *AgentRPC) GetWorkloadStatus(ctx context.Context, opts *pb.GetWorkloadStatusOptions) (*pb.WorkloadStatus, error) {
+	if opts.Id == "" {
+		return nil, grpcstatus.Error(codes.InvalidArgument, "id is required")
+	}
+	status, err := a.workloadsManager.GetWorkloadStatus(ctx, opts.Id)
+	if err != nil {
+		return nil, grpcstatus.Error(codes.Internal, err.Error())
+	}
+	return toRPCWorkloadStatus(status), nil
+}
+
+// WatchEvents streams the workload events seen by the monitor
+func (a *AgentRPC) WatchEvents(_ *pb.Empty, stream pb.AgentRPC_WatchEventsServer) error {
+	ctx, cancel := a.streamContext(stream.Context())
+	defer cancel()
+	for event := range a.workloadsManager.WatchEvents(ctx) {
+		if err := stream.Send(toRPCWorkloadEvent(event)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Serve starts the gRPC service
+// GRPCAddr can be a unix socket, which is served without TLS
+// blocks by grpc.Server.Serve, stops gracefully when ctx is done
+// run this in a separated goroutine
+func (a *AgentRPC) Serve(ctx context.Context) {
+	if a.config.API.GRPCAddr == "" {
+		return
+	}
+	logger := log.WithFunc("serve")
+
+	lis, err := api.Listen(a.config.API.GRPCAddr, a.config.API.Socket)
+	if err != nil {
+		logger.Error(nil, err, "grpc api listen failed") //nolint
+		return
+	}
+	server := a.newServer(!api.IsUnix(lis.Addr()))
+	logger.Infof(nil, "grpc api started %s", a.config.API.GRPCAddr) //nolint
+
+	done := make(chan struct{})
+	_ = utils.Pool.Submit(func() {
+		defer close(done)
+		<-ctx.Done()
+		close(a.stop)
+		stopped := make(chan struct{})
+		_ = utils.Pool.Submit(func() {
+			defer close(stopped)
+			server.GracefulStop()
+		})
+		timeout := a.config.API.ShutdownTimeout
+		if timeout <= 0 {
+			timeout = defaultShutdownTimeout
+		}
+		select {
+		case <-stopped:
+		case <-time.After(timeout):
+			server.Stop()
+		}
+	})
+
+	if err := server.Serve(lis); err != nil {
+		logger.Error(nil, err, "grpc api start failed") //nolint
+		return
+	}
+	<-done
+	logger.Info(nil, "grpc api stopped") //nolint
+}
+
+// streamContext returns a context canceled when stopping
+func (a *AgentRPC) streamContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	_ = utils.Pool.Submit(func() {
+		select {
+		case <-ctx.Done():
+		case <-a.stop:
+			cancel()
+		}
+	})
+	return ctx, cancel
+}
+
+func (a *AgentRPC) newServer(secure bool) *grpc.Server {
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(a.unaryInterceptor),
+		grpc.StreamInterceptor(a.streamInterceptor),
+	}
+	if a.tls != nil && secure {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(a.tls.Config())))
+	}
+	server := grpc.NewServer(opts...)
+	pb.RegisterAgentRPCServer(server, a)
+	return server
+}
+
+// scopes of methods, the other methods require workloads scope
+var scopes = map[string]string{
+	pb.AgentRPC_StreamLogs_FullMethodName: common.ScopeLogs,
+}
+
+// authorize checks the credentials in authorization metadata, the same as HTTP API
+func (a *AgentRPC) authorize(ctx context.Context, method string) error {
+	// file permission of the socket is the access control of trusted unix sockets
+	if p, ok := peer.FromContext(ctx); ok && a.config.API.Socket.Trusted && api.IsUnix(p.Addr) {
+		return nil
+	}
+	scope, ok := scopes[method]
+	if !ok {
+		scope = common.ScopeWorkloads
+	}
+	authorization := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+	switch err := a.authenticator.Authorize(authorization, scope); err {
+	case nil:
+		return nil
+	case common.ErrUnauthenticated:
+		return grpcstatus.Error(codes.Unauthenticated, err.Error())
+	default:
+		return grpcstatus.Error(codes.PermissionDenied, err.Error())
+	}
+}
+
+func (a *AgentRPC) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := a.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *AgentRPC) streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authorize(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// logStream sends logs into a gRPC stream
+type logStream struct {
+	stream pb.AgentRPC_StreamLogsServer
+}
+
+func (s *logStream) Write(data []byte) error {
+	log := &types.Log{}
+	if err := json.Unmarshal(data, log); err != nil {
+		return err
+	}
+	return s.stream.Send(toRPCLog(log))
+}
+
+// Heartbeat does nothing, gRPC keeps the connection alive by itself
+func (s *logStream) Heartbeat() error {
+	return nil
+}
@@ -0,0 +1,170 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/projecteru2/agent/common"
+	"github.com/projecteru2/agent/manager/workload"
+	pb "github.com/projecteru2/agent/rpc/gen"
+	"github.com/projecteru2/agent/types"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestClient(t *testing.T, auth types.APIAuthConfig) pb.AgentRPCClient {
+	config := &types.Config{
+		API:                     types.APIConfig{Auth: auth},
+		HostName:                "fake",
+		Store:                   common.MocksStore,
+		Runtime:                 common.MocksRuntime,
+		HealthCheck:             types.HealthCheckConfig{Interval: 10, Timeout: 5, CacheTTL: 300},
+		GlobalConnectionTimeout: 5 * time.Second,
+	}
+	manager, err := workload.NewManager(context.Background(), config)
+	assert.NoError(t, err)
+
+	lis := bufconn.Listen(1 << 20)
+	server := New(config, manager, nil).newServer(true)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewAgentRPCClient(conn)
+}
+
+func TestWorkloadStatus(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, types.APIAuthConfig{})
+
+	statuses, err := client.ListWorkloads(ctx, &pb.Empty{})
+	assert.NoError(t, err)
+	IDs := []string{}
+	for _, status := range statuses.Statuses {
+		IDs = append(IDs, status.Id)
+	}
+	sort.Strings(IDs)
+	assert.Equal(t, []string{"Asuka", "Rei", "Shinji"}, IDs)
+
+	status, err := client.GetWorkloadStatus(ctx, &pb.GetWorkloadStatusOptions{Id: "Shinji"})
+	assert.NoError(t, err)
+	assert.True(t, status.Running)
+	assert.True(t, status.Healthy)
+
+	_, err = client.GetWorkloadStatus(ctx, &pb.GetWorkloadStatusOptions{})
+	assert.Equal(t, codes.InvalidArgument, grpcstatus.Code(err))
+}
+
+func TestStreamLogs(t *testing.T) {
+	client := newTestClient(t, types.APIAuthConfig{})
+
+	for _, opts := range []*pb.StreamLogsOptions{{}, {App: "nerv", Tail: -1}, {App: "nerv", Regex: "("}} {
+		stream, err := client.StreamLogs(context.Background(), opts)
+		assert.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.InvalidArgument, grpcstatus.Code(err))
+	}
+}
+
+func TestAuth(t *testing.T) {
+	client := newTestClient(t, types.APIAuthConfig{
+		Credentials: []types.APICredential{{Token: "rei", Scopes: []string{common.ScopeLogs}}},
+	})
+	ctx := context.Background()
+
+	_, err := client.ListWorkloads(ctx, &pb.Empty{})
+	assert.Equal(t, codes.Unauthenticated, grpcstatus.Code(err))
+	_, err = client.ListWorkloads(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer rei"), &pb.Empty{})
+	assert.Equal(t, codes.PermissionDenied, grpcstatus.Code(err))
+
+	stream, err := client.StreamLogs(ctx, &pb.StreamLogsOptions{App: "nerv"})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, grpcstatus.Code(err))
+	// passes auth, and fails on the arguments
+	stream, err = client.StreamLogs(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer rei"), &pb.StreamLogsOptions{})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, grpcstatus.Code(err))
+}
+
+func TestServeShutdown(t *testing.T) {
+	config := &types.Config{
+		HostName:                "fake",
+		Store:                   common.MocksStore,
+		Runtime:                 common.MocksRuntime,
+		GlobalConnectionTimeout: 5 * time.Second,
+		API:                     types.APIConfig{GRPCAddr: "127.0.0.1:0"},
+	}
+	manager, err := workload.NewManager(context.Background(), config)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		New(config, manager, nil).Serve(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("grpc api is not stopped")
+	}
+}
+
+func TestServeUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	config := &types.Config{
+		HostName:                "fake",
+		Store:                   common.MocksStore,
+		Runtime:                 common.MocksRuntime,
+		GlobalConnectionTimeout: 5 * time.Second,
+		API: types.APIConfig{
+			GRPCAddr: "unix://" + path,
+			Auth:     types.APIAuthConfig{Credentials: []types.APICredential{{Token: "rei"}}},
+			Socket:   types.APISocketConfig{Trusted: true},
+		},
+	}
+	manager, err := workload.NewManager(context.Background(), config)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		New(config, manager, nil).Serve(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	conn, err := grpc.Dial("unix://"+path, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewAgentRPCClient(conn)
+	// requests from trusted sockets skip auth
+	assert.Eventually(t, func() bool {
+		_, err := client.ListWorkloads(context.Background(), &pb.Empty{})
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	config.API.Socket.Trusted = false
+	_, err = client.ListWorkloads(context.Background(), &pb.Empty{})
+	assert.Equal(t, codes.Unauthenticated, grpcstatus.Code(err))
+}
@@ -0,0 +1,75 @@
+package rpc
+
+import (
+	"regexp"
+	"time"
+
+	pb "github.com/projecteru2/agent/rpc/gen"
+	"github.com/projecteru2/agent/types"
+
+	"github.com/pkg/errors"
+)
+
+func toLogQuery(opts *pb.StreamLogsOptions) (*types.LogQuery, error) {
+	query := &types.LogQuery{
+		App:        opts.App,
+		Tail:       int(opts.Tail),
+		EntryPoint: opts.Entrypoint,
+		Ident:      opts.Ident,
+		ID:         opts.Id,
+		Type:       opts.Type,
+		Contains:   opts.Contains,
+	}
+	if query.App == "" {
+		return nil, errors.New("app is required")
+	}
+	if query.Tail < 0 {
+		return nil, errors.Errorf("invalid tail %d", opts.Tail)
+	}
+	if opts.Since > 0 {
+		query.Since = time.Unix(0, opts.Since)
+	}
+	if opts.Regex != "" {
+		re, err := regexp.Compile(opts.Regex)
+		if err != nil {
+			return nil, err
+		}
+		query.Regexp = re
+	}
+	return query, nil
+}
+
+func toRPCLog(log *types.Log) *pb.Log {
+	return &pb.Log{
+		Id:         log.ID,
+		Name:       log.Name,
+		Type:       log.Type,
+		Entrypoint: log.EntryPoint,
+		Ident:      log.Ident,
+		Data:       log.Data,
+		Datetime:   log.Datetime,
+		Extra:      log.Extra,
+	}
+}
+
+func toRPCWorkloadStatus(status *types.WorkloadStatus) *pb.WorkloadStatus {
+	return &pb.WorkloadStatus{
+		Id:         status.ID,
+		Running:    status.Running,
+		Healthy:    status.Healthy,
+		Networks:   status.Networks,
+		Extension:  status.Extension,
+		Appname:    status.Appname,
+		Nodename:   status.Nodename,
+		Entrypoint: status.Entrypoint,
+	}
+}
+
+func toRPCWorkloadEvent(event *types.WorkloadEventMessage) *pb.WorkloadEvent {
+	return &pb.WorkloadEvent{
+		Id:       event.ID,
+		Type:     event.Type,
+		Action:   event.Action,
+		TimeNano: event.TimeNano,
+	}
+}
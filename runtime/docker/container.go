@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/projecteru2/agent/types"
 	"github.com/projecteru2/agent/utils"
 	coretypes "github.com/projecteru2/core/types"
 )
@@ -22,27 +23,33 @@ type Container struct {
 	Memory      int64
 	Labels      map[string]string
 	Env         map[string]string
-	HealthCheck *coretypes.HealthCheck
+	HealthCheck *types.HealthCheck
 	LocalIP     string `json:"-"`
 }
 
-// CheckHealth check container's health status
-func (c *Container) CheckHealth(ctx context.Context, timeout time.Duration) bool {
+// CheckHealth check container's health status, exec runs the command check inside the container
+func (c *Container) CheckHealth(ctx context.Context, timeout time.Duration, exec utils.ExecFunc) bool {
 	if c.HealthCheck == nil {
 		return true
 	}
 	var tcpChecker []string
 	var httpChecker []string
+	var grpcChecker []string
 
 	for _, port := range c.HealthCheck.TCPPorts {
 		tcpChecker = append(tcpChecker, fmt.Sprintf("%s:%s", c.LocalIP, port))
 	}
 	if c.HealthCheck.HTTPPort != "" {
-		httpChecker = append(httpChecker, fmt.Sprintf("http://%s:%s%s", c.LocalIP, c.HealthCheck.HTTPPort, c.HealthCheck.HTTPURL))
+		httpChecker = append(httpChecker, fmt.Sprintf("%s://%s:%s%s", c.HealthCheck.HTTPScheme(), c.LocalIP, c.HealthCheck.HTTPPort, c.HealthCheck.HTTPURL))
+	}
+	if c.HealthCheck.GRPC != nil {
+		grpcChecker = append(grpcChecker, fmt.Sprintf("%s:%s", c.LocalIP, c.HealthCheck.GRPC.Port))
 	}
 
 	ID := c.ID
-	f1 := utils.CheckHTTP(ctx, ID, httpChecker, c.HealthCheck.HTTPCode, timeout)
+	f1 := utils.CheckHTTP(ctx, ID, httpChecker, c.HealthCheck.HTTPCode, c.HealthCheck.HTTP, timeout)
 	f2 := utils.CheckTCP(ctx, ID, tcpChecker, timeout)
-	return f1 && f2
+	f3 := utils.CheckCmd(ctx, ID, c.HealthCheck.Cmd, exec, timeout)
+	f4 := utils.CheckGRPC(ctx, ID, grpcChecker, c.HealthCheck.GRPC, timeout)
+	return f1 && f2 && f3 && f4
 }
@@ -11,13 +11,11 @@ import (
 	"os"
 	"runtime"
 	"strings"
-	"time"
 
 	"github.com/projecteru2/agent/common"
 	"github.com/projecteru2/agent/types"
 	"github.com/projecteru2/agent/utils"
 	"github.com/projecteru2/core/cluster"
-	coreutils "github.com/projecteru2/core/utils"
 	"github.com/vishvananda/netns"
 
 	enginetypes "github.com/docker/docker/api/types"
@@ -225,7 +223,7 @@ func (d *Docker) detectWorkload(ctx context.Context, ID string) (*Container, err
 	}
 
 	// 生成基准 meta
-	meta := coreutils.DecodeMetaInLabel(ctx, label)
+	meta := utils.DecodeMetaInLabel(ctx, label)
 
 	// 是否符合 eru pattern，如果一个容器又有 ERUMark 又是三段式的 name，那它就是个 ERU 容器
 	container, err := generateContainerMeta(ctx, c, meta, label)
@@ -339,12 +337,42 @@ func (d *Docker) GetStatus(ctx context.Context, ID string, checkHealth bool) (*t
 			return nil, common.ErrGetLockFailed
 		}
 		defer free()
-		status.Healthy = container.CheckHealth(ctx, time.Duration(d.config.HealthCheck.Timeout)*time.Second)
+		status.Healthy = container.CheckHealth(ctx, utils.GetHealthCheckTimeout(d.config, container.Labels), func(ctx context.Context, cmd []string) (int, error) {
+			return d.execWorkload(ctx, container.ID, cmd)
+		})
 	}
 
 	return status, nil
 }
 
+// execWorkload runs the command inside the container, returns the exit code after it exits
+func (d *Docker) execWorkload(ctx context.Context, ID string, cmd []string) (int, error) {
+	exec, err := d.client.ContainerExecCreate(ctx, ID, enginetypes.ExecConfig{Cmd: cmd, AttachStdout: true, AttachStderr: true})
+	if err != nil {
+		return 0, err
+	}
+	resp, err := d.client.ContainerExecAttach(ctx, exec.ID, enginetypes.ExecStartCheck{})
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = resp.Conn.SetDeadline(deadline)
+	}
+	// the output is closed when the command exits
+	if _, err := io.Copy(io.Discard, resp.Reader); err != nil {
+		return 0, err
+	}
+	inspect, err := d.client.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return 0, err
+	}
+	if inspect.Running {
+		return 0, common.ErrExecNotExited
+	}
+	return inspect.ExitCode, nil
+}
+
 // GetWorkloadName returns the name of workload
 func (d *Docker) GetWorkloadName(ctx context.Context, ID string) (string, error) {
 	var containerJSON enginetypes.ContainerJSON
@@ -360,6 +388,16 @@ func (d *Docker) GetWorkloadName(ctx context.Context, ID string) (string, error)
 	return containerJSON.Name, nil
 }
 
+// GetWorkloadLabels returns the labels of workload
+func (d *Docker) GetWorkloadLabels(ctx context.Context, ID string) (map[string]string, error) {
+	container, err := d.detectWorkload(ctx, ID)
+	if err != nil {
+		log.WithFunc("GetWorkloadLabels").WithField("ID", ID).Error(ctx, err, "failed to detect container")
+		return nil, err
+	}
+	return container.Labels, nil
+}
+
 // LogFieldsExtra .
 func (d *Docker) LogFieldsExtra(ctx context.Context, ID string) (map[string]string, error) {
 	container, err := d.detectWorkload(ctx, ID)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 
+	"github.com/projecteru2/agent/types"
 	"github.com/projecteru2/agent/utils"
 	coretypes "github.com/projecteru2/core/types"
 	coreutils "github.com/projecteru2/core/utils"
@@ -26,7 +27,7 @@ func normalizeEnv(env []string) map[string]string {
 }
 
 // generateContainerMeta make meta obj
-func generateContainerMeta(ctx context.Context, c enginetypes.ContainerJSON, meta *coretypes.LabelMeta, labels map[string]string) (*Container, error) {
+func generateContainerMeta(ctx context.Context, c enginetypes.ContainerJSON, meta *types.LabelMeta, labels map[string]string) (*Container, error) {
 	name, entrypoint, ident, err := utils.GetAppInfo(c.Name)
 	if err != nil {
 		return nil, err
@@ -101,6 +101,29 @@ func (_m *Runtime) GetStatus(ctx context.Context, ID string, checkHealth bool) (
 	return r0, r1
 }
 
+// GetWorkloadLabels provides a mock function with given fields: ctx, ID
+func (_m *Runtime) GetWorkloadLabels(ctx context.Context, ID string) (map[string]string, error) {
+	ret := _m.Called(ctx, ID)
+
+	var r0 map[string]string
+	if rf, ok := ret.Get(0).(func(context.Context, string) map[string]string); ok {
+		r0 = rf(ctx, ID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, ID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetWorkloadName provides a mock function with given fields: ctx, ID
 func (_m *Runtime) GetWorkloadName(ctx context.Context, ID string) (string, error) {
 	ret := _m.Called(ctx, ID)
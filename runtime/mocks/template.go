@@ -23,6 +23,7 @@ type eva struct {
 	Pid        int
 	Running    bool
 	Healthy    bool
+	Labels     map[string]string
 }
 
 // Nerv a fake runtime
@@ -125,6 +126,13 @@ func FromTemplate() runtime.Runtime {
 		}
 		return workload.Name
 	}, nil)
+	n.On("GetWorkloadLabels", mock.Anything, mock.Anything).Return(func(ctx context.Context, ID string) map[string]string {
+		workload, ok := n.workloads.Get(ID)
+		if !ok || workload.Labels == nil {
+			return map[string]string{}
+		}
+		return workload.Labels
+	}, nil)
 	n.On("LogFieldsExtra", mock.Anything, mock.Anything).Return(map[string]string{}, nil)
 	n.On("IsDaemonRunning", mock.Anything).Return(func(ctx context.Context) bool {
 		return n.daemonRunning
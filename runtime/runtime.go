@@ -15,6 +15,7 @@ type Runtime interface {
 	Events(ctx context.Context, filters map[string]string) (<-chan *types.WorkloadEventMessage, <-chan error)
 	GetStatus(ctx context.Context, ID string, checkHealth bool) (*types.WorkloadStatus, error)
 	GetWorkloadName(ctx context.Context, ID string) (string, error)
+	GetWorkloadLabels(ctx context.Context, ID string) (map[string]string, error)
 	LogFieldsExtra(ctx context.Context, ID string) (map[string]string, error)
 	IsDaemonRunning(ctx context.Context) bool
 	Name() string
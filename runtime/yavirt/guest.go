@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/projecteru2/agent/types"
 	"github.com/projecteru2/agent/utils"
 
 	"github.com/projecteru2/core/log"
@@ -15,19 +16,6 @@ import (
 // LabelMeta .
 const LabelMeta = "ERU_META"
 
-// HealthCheck .
-type HealthCheck struct {
-	TCPPorts []string
-	HTTPPort string
-	HTTPURL  string
-	HTTPCode int
-}
-
-type healthCheckMeta struct {
-	Publish     []string
-	HealthCheck *HealthCheck
-}
-
 // Guest yavirt virtual machine
 type Guest struct {
 	ID            string
@@ -47,17 +35,17 @@ type Guest struct {
 	IPs           []string
 	Hostname      string
 	Running       bool
-	HealthCheck   *HealthCheck
+	HealthCheck   *types.HealthCheck
 
 	once sync.Once
 }
 
-// CheckHealth returns if the guest is healthy
-func (g *Guest) CheckHealth(ctx context.Context, timeout time.Duration) bool {
+// CheckHealth returns if the guest is healthy, exec runs the command check through the guest exec channel
+func (g *Guest) CheckHealth(ctx context.Context, timeout time.Duration, exec utils.ExecFunc) bool {
 	// init health check bridge
 	g.once.Do(func() {
 		if meta, ok := g.Labels[LabelMeta]; ok {
-			hcm := &healthCheckMeta{}
+			hcm := &types.LabelMeta{}
 			err := json.Unmarshal([]byte(meta), hcm)
 			if err != nil {
 				log.WithFunc("CheckHealth").Error(ctx, err, "invalid json format, guest %v, meta %v", g.ID, meta)
@@ -73,6 +61,7 @@ func (g *Guest) CheckHealth(ctx context.Context, timeout time.Duration) bool {
 
 	var tcpChecker []string
 	var httpChecker []string
+	var grpcChecker []string
 
 	healthCheck := g.HealthCheck
 
@@ -83,11 +72,19 @@ func (g *Guest) CheckHealth(ctx context.Context, timeout time.Duration) bool {
 	}
 	if healthCheck.HTTPPort != "" {
 		for _, ip := range g.IPs {
-			httpChecker = append(httpChecker, fmt.Sprintf("http://%s:%s%s", ip, healthCheck.HTTPPort, healthCheck.HTTPURL))
+			httpChecker = append(httpChecker, fmt.Sprintf("%s://%s:%s%s", healthCheck.HTTPScheme(), ip, healthCheck.HTTPPort, healthCheck.HTTPURL))
+		}
+	}
+
+	if healthCheck.GRPC != nil {
+		for _, ip := range g.IPs {
+			grpcChecker = append(grpcChecker, fmt.Sprintf("%s:%s", ip, healthCheck.GRPC.Port))
 		}
 	}
 
-	f1 := utils.CheckHTTP(ctx, g.ID, httpChecker, healthCheck.HTTPCode, timeout)
+	f1 := utils.CheckHTTP(ctx, g.ID, httpChecker, healthCheck.HTTPCode, healthCheck.HTTP, timeout)
 	f2 := utils.CheckTCP(ctx, g.ID, tcpChecker, timeout)
-	return f1 && f2
+	f3 := utils.CheckCmd(ctx, g.ID, healthCheck.Cmd, exec, timeout)
+	f4 := utils.CheckGRPC(ctx, g.ID, grpcChecker, healthCheck.GRPC, timeout)
+	return f1 && f2 && f3 && f4
 }
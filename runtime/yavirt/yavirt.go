@@ -7,7 +7,6 @@ import (
 	"regexp"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/projecteru2/agent/common"
 	"github.com/projecteru2/agent/types"
@@ -192,17 +191,38 @@ func (y *Yavirt) GetStatus(ctx context.Context, ID string, checkHealth bool) (*t
 			return nil, common.ErrGetLockFailed
 		}
 		defer free()
-		status.Healthy = guest.CheckHealth(ctx, time.Duration(y.config.HealthCheck.Timeout)*time.Second)
+		status.Healthy = guest.CheckHealth(ctx, utils.GetHealthCheckTimeout(y.config, guest.Labels), func(ctx context.Context, cmd []string) (int, error) {
+			return y.execWorkload(ctx, guest.ID, cmd)
+		})
 	}
 
 	return status, nil
 }
 
+// execWorkload runs the command inside the guest through the guest exec channel, returns the exit code
+func (y *Yavirt) execWorkload(ctx context.Context, ID string, cmd []string) (int, error) {
+	msg, err := y.client.ExecuteGuest(ctx, ID, cmd)
+	if err != nil {
+		return 0, err
+	}
+	return msg.ExitCode, nil
+}
+
 // GetWorkloadName not implemented yet
 func (y *Yavirt) GetWorkloadName(context.Context, string) (string, error) {
 	return "", common.ErrNotImplemented
 }
 
+// GetWorkloadLabels returns the labels of guest
+func (y *Yavirt) GetWorkloadLabels(ctx context.Context, ID string) (map[string]string, error) {
+	guest, err := y.detectWorkload(ctx, ID)
+	if err != nil {
+		log.WithFunc("GetWorkloadLabels").WithField("ID", ID).Error(ctx, err, "failed to detect guest")
+		return nil, err
+	}
+	return guest.Labels, nil
+}
+
 // LogFieldsExtra .
 func (y *Yavirt) LogFieldsExtra(context.Context, string) (map[string]string, error) {
 	return map[string]string{}, nil
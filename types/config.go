@@ -7,6 +7,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/projecteru2/agent/common"
 	coretypes "github.com/projecteru2/core/types"
 
 	"github.com/projecteru2/core/log"
@@ -32,21 +33,194 @@ type MetricsConfig struct {
 }
 
 // APIConfig contain api config
+// Addr and GRPCAddr can be tcp addresses or unix:///path/to/socket, Addrs are more addresses of HTTP API.
+// Token is a bearer token granted all scopes, AllowedOrigins are the origins allowed to open websocket log streams besides the same origin
 type APIConfig struct {
-	Addr string `yaml:"addr"`
+	Addr      string          `yaml:"addr"`
+	Addrs     []string        `yaml:"addrs"`
+	GRPCAddr  string          `yaml:"grpc_addr"`
+	Socket    APISocketConfig `yaml:"socket"`
+	Token     string          `yaml:"token"`
+	Heartbeat time.Duration   `yaml:"heartbeat" default:"30s"`
+	TLS       APITLSConfig    `yaml:"tls"`
+	Auth      APIAuthConfig   `yaml:"auth"`
+
+	AllowedOrigins  []string      `yaml:"allowed_origins"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" default:"10s"`
+}
+
+// APISocketConfig contain config for unix sockets of api
+// Owner is user:group, Trusted grants all scopes to requests from the sockets, then file permission is the access control
+type APISocketConfig struct {
+	Mode    string `yaml:"mode" default:"0660"`
+	Owner   string `yaml:"owner"`
+	Trusted bool   `yaml:"trusted"`
+}
+
+// APITLSConfig contain tls config for api, ClientCA enables mutual TLS
+type APITLSConfig struct {
+	Cert     string `yaml:"cert"`
+	Key      string `yaml:"key"`
+	ClientCA string `yaml:"client_ca"`
+}
+
+// APIAuthConfig contain auth config for api
+// Anonymous are the scopes allowed without credentials
+type APIAuthConfig struct {
+	Credentials []APICredential `yaml:"credentials"`
+	Anonymous   []string        `yaml:"anonymous"`
+}
+
+// APICredential is a bearer token, or username and password of basic auth, granted the scopes
+type APICredential struct {
+	Token    string   `yaml:"token"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	Scopes   []string `yaml:"scopes"`
+}
+
+// LogSpoolConfig contain log spool config
+type LogSpoolConfig struct {
+	Dir          string        `yaml:"dir"`
+	MaxBytes     int64         `yaml:"max_bytes" default:"104857600"`
+	SegmentBytes int64         `yaml:"segment_bytes" default:"8388608"`
+	MaxAge       time.Duration `yaml:"max_age" default:"24h"`
+}
+
+// LogRouteConfig routes logs matching the rule to the forwards
+// empty fields match anything, Name and EntryPoint accept shell patterns, Labels match the workload's labels
+type LogRouteConfig struct {
+	Name       string            `yaml:"name"`
+	EntryPoint string            `yaml:"entrypoint"`
+	Extra      map[string]string `yaml:"extra"`
+	Labels     map[string]string `yaml:"labels"`
+	Forwards   []string          `yaml:"forwards"`
+}
+
+// LogTLSConfig contain tls config for tls log forwards
+// Cert and Key are used for mutual TLS, ServerName defaults to the host of forward
+type LogTLSConfig struct {
+	CA         string `yaml:"ca"`
+	Cert       string `yaml:"cert"`
+	Key        string `yaml:"key"`
+	ServerName string `yaml:"server_name"`
+}
+
+// LogHTTPConfig contain config for http log forwards
+type LogHTTPConfig struct {
+	BatchSize  int               `yaml:"batch_size" default:"1000"`
+	BatchBytes int               `yaml:"batch_bytes" default:"1048576"`
+	BatchWait  time.Duration     `yaml:"batch_wait" default:"1s"`
+	QueueSize  int               `yaml:"queue_size" default:"10000"`
+	Timeout    time.Duration     `yaml:"timeout" default:"10s"`
+	MaxRetries int               `yaml:"max_retries" default:"5"`
+	MinBackoff time.Duration     `yaml:"min_backoff" default:"500ms"`
+	MaxBackoff time.Duration     `yaml:"max_backoff" default:"30s"`
+	Headers    map[string]string `yaml:"headers"`
+}
+
+// LogKafkaConfig contain config for kafka log forwards
+// Acks can be none, leader or all
+type LogKafkaConfig struct {
+	Acks       string        `yaml:"acks" default:"leader"`
+	BatchSize  int           `yaml:"batch_size" default:"1000"`
+	BatchBytes int           `yaml:"batch_bytes" default:"1048576"`
+	BatchWait  time.Duration `yaml:"batch_wait" default:"1s"`
+	QueueSize  int           `yaml:"queue_size" default:"10000"`
+	Timeout    time.Duration `yaml:"timeout" default:"10s"`
+}
+
+// LogMultilineConfig merges continuation lines into the previous log,
+// a line is a continuation if it matches one of Continuation, or it's indented when Indent is true
+type LogMultilineConfig struct {
+	Continuation []string      `yaml:"continuation"`
+	Indent       bool          `yaml:"indent"`
+	MaxLines     int           `yaml:"max_lines" default:"500"`
+	MaxBytes     int           `yaml:"max_bytes" default:"65536"`
+	FlushTimeout time.Duration `yaml:"flush_timeout" default:"1s"`
+}
+
+// LogParserConfig contain config for parsing JSON or logfmt logs
+// Promote are the keys promoted into Extra of logs
+type LogParserConfig struct {
+	Promote []string `yaml:"promote"`
+}
+
+// LogRateLimitConfig contain config for limiting logs of each workload
+// Rate is lines per second, 0 means unlimited. Mode can be drop or sample,
+// in sample mode one of every SampleRate lines exceeding the limit is kept.
+type LogRateLimitConfig struct {
+	Rate           float64       `yaml:"rate"`
+	Burst          int           `yaml:"burst"`
+	Mode           string        `yaml:"mode" default:"drop"`
+	SampleRate     int           `yaml:"sample_rate" default:"100"`
+	ReportInterval time.Duration `yaml:"report_interval" default:"10s"`
+}
+
+// LogRedactionConfig contain config for redacting secrets in logs
+// Rules is the file of redaction rules, it's reloaded when modified, checked every ReloadInterval
+type LogRedactionConfig struct {
+	Builtin        bool          `yaml:"builtin"`
+	Rules          string        `yaml:"rules"`
+	ReloadInterval time.Duration `yaml:"reload_interval" default:"10s"`
+}
+
+// LogRedactionRule replaces the matches of Pattern with Replacement, which can refer to submatches like ${1}
+type LogRedactionRule struct {
+	Name        string `yaml:"name"`
+	Pattern     string `yaml:"pattern"`
+	Replacement string `yaml:"replacement"`
+}
+
+// LogHistoryConfig contain config for the recent logs kept in memory for each app,
+// which are replayed to subscribers of /log/, 0 means no limit, both 0 disables history
+type LogHistoryConfig struct {
+	Lines int `yaml:"lines" default:"1000"`
+	Bytes int `yaml:"bytes" default:"1048576"`
+}
+
+// LogBroadcastConfig contain config for broadcasting logs to subscribers of /log/,
+// buffer is the number of logs waiting for broadcasting, queue_size is the number of logs waiting for each subscriber,
+// policy is what to do with a subscriber whose queue is full: drop the log, or evict the subscriber
+type LogBroadcastConfig struct {
+	Buffer    int    `yaml:"buffer" default:"10000"`
+	QueueSize int    `yaml:"queue_size" default:"1000"`
+	Policy    string `yaml:"policy" default:"drop"`
 }
 
 // LogConfig contain log config
 type LogConfig struct {
-	Forwards []string `yaml:"forwards"`
-	Stdout   bool     `yaml:"stdout"`
+	Forwards []string         `yaml:"forwards"`
+	Routes   []LogRouteConfig `yaml:"routes"`
+	Default  []string         `yaml:"default"`
+	Stdout   bool             `yaml:"stdout"`
+	Spool    LogSpoolConfig   `yaml:"spool"`
+	TLS      LogTLSConfig     `yaml:"tls"`
+	HTTP     LogHTTPConfig    `yaml:"http"`
+	Kafka    LogKafkaConfig   `yaml:"kafka"`
+
+	Multiline map[string]LogMultilineConfig `yaml:"multiline"`
+	Parser    LogParserConfig               `yaml:"parser"`
+	RateLimit LogRateLimitConfig            `yaml:"rate_limit"`
+	Redaction LogRedactionConfig            `yaml:"redaction"`
+	History   LogHistoryConfig              `yaml:"history"`
+	Broadcast LogBroadcastConfig            `yaml:"broadcast"`
 }
 
 // HealthCheckConfig contain healthcheck config
+// Healthy changes only after HealthyThreshold consecutive successes or UnhealthyThreshold consecutive failures,
+// failures in StartPeriod after a workload starts are not counted.
+// MaxInFlight limits the health checks running at the same time
 type HealthCheckConfig struct {
 	Interval int   `yaml:"interval" default:"60"`
 	Timeout  int   `yaml:"timeout" default:"10"`
 	CacheTTL int64 `yaml:"cache_ttl" default:"300"`
+
+	HealthyThreshold   int           `yaml:"healthy_threshold" default:"1"`
+	UnhealthyThreshold int           `yaml:"unhealthy_threshold" default:"1"`
+	StartPeriod        time.Duration `yaml:"start_period"`
+
+	MaxInFlight int `yaml:"max_in_flight" default:"64"`
 }
 
 // Config contain all configs
@@ -127,12 +301,21 @@ func (config *Config) Prepare(c *cli.Context) {
 	if c.String("api-addr") != "" {
 		config.API.Addr = c.String("api-addr")
 	}
+	if len(c.StringSlice("api-addrs")) > 0 {
+		config.API.Addrs = c.StringSlice("api-addrs")
+	}
+	if c.String("api-grpc-addr") != "" {
+		config.API.GRPCAddr = c.String("api-grpc-addr")
+	}
 	if len(c.StringSlice("log-forwards")) > 0 {
 		config.Log.Forwards = c.StringSlice("log-forwards")
 	}
 	if c.String("log-stdout") != "" {
 		config.Log.Stdout = c.String("log-stdout") == "yes"
 	}
+	if c.String("log-spool-dir") != "" {
+		config.Log.Spool.Dir = c.String("log-spool-dir")
+	}
 	if c.Bool("check-only-mine") {
 		config.CheckOnlyMine = true
 	}
@@ -143,6 +326,11 @@ func (config *Config) Prepare(c *cli.Context) {
 		config.Store = c.String("store")
 	}
 	// validate
+	for _, scope := range config.API.Auth.Anonymous {
+		if scope == common.ScopeAdmin || scope == common.ScopeAll {
+			log.WithFunc("Prepare").Fatalf(c.Context, common.ErrInvalidAnonymousScope, "scope %s can't be allowed without credentials", scope)
+		}
+	}
 	if config.PidFile == "" {
 		config.PidFile = "./agent.pid"
 	}
@@ -155,6 +343,15 @@ func (config *Config) Prepare(c *cli.Context) {
 	if config.HealthCheck.CacheTTL == 0 {
 		config.HealthCheck.CacheTTL = 300
 	}
+	if config.HealthCheck.HealthyThreshold <= 0 {
+		config.HealthCheck.HealthyThreshold = 1
+	}
+	if config.HealthCheck.UnhealthyThreshold <= 0 {
+		config.HealthCheck.UnhealthyThreshold = 1
+	}
+	if config.HealthCheck.MaxInFlight <= 0 {
+		config.HealthCheck.MaxInFlight = 64
+	}
 }
 
 // Print config
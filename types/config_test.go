@@ -22,6 +22,10 @@ func TestLoadConfig(t *testing.T) {
 	assert.Equal(config.HealthCheck.Interval, 120)
 	assert.Equal(config.HealthCheck.Timeout, 10)
 	assert.Equal(config.HealthCheck.CacheTTL, int64(300))
+	assert.Equal(config.HealthCheck.HealthyThreshold, 2)
+	assert.Equal(config.HealthCheck.UnhealthyThreshold, 3)
+	assert.Equal(config.HealthCheck.StartPeriod, 30*time.Second)
+	assert.Equal(config.HealthCheck.MaxInFlight, 32)
 	assert.Equal(config.GetHealthCheckStatusTTL(), int64(0))
 
 	assert.Equal(config.Store, "grpc")
@@ -29,5 +33,17 @@ func TestLoadConfig(t *testing.T) {
 
 	assert.Equal(config.GlobalConnectionTimeout, time.Second*15)
 
+	// the options requiring files or secrets of the host are commented out
+	assert.Empty(config.API.Token)
+	assert.Empty(config.API.TLS.Cert)
+	assert.Empty(config.API.Auth.Credentials)
+	assert.Empty(config.Log.TLS.CA)
+	assert.Empty(config.Log.Redaction.Rules)
+	assert.Equal(config.API.Auth.Anonymous, []string{"metrics"})
+	assert.Equal(config.API.Addrs, []string{"unix:///run/eru-agent.sock"})
+	assert.Equal(config.API.Socket.Mode, "0660")
+	assert.Equal(config.API.Socket.Owner, "root:docker")
+	assert.False(config.API.Socket.Trusted)
+
 	config.Print()
 }
@@ -0,0 +1,62 @@
+package types
+
+// LabelMeta is the meta of workload stored in ERU_META label,
+// it's compatible with the one of core, with more kinds of health checks
+type LabelMeta struct {
+	Publish     []string
+	HealthCheck *HealthCheck
+}
+
+// HealthCheck defines how to check the health of a workload,
+// the workload is healthy only if all the checks pass
+type HealthCheck struct {
+	TCPPorts []string
+	HTTPPort string
+	HTTPURL  string
+	HTTPCode int
+	HTTP     *HTTPHealthCheck `json:",omitempty"`
+	Cmd      *CmdHealthCheck  `json:",omitempty"`
+	GRPC     *GRPCHealthCheck `json:",omitempty"`
+}
+
+// HTTPScheme returns the scheme of HTTP check
+func (h *HealthCheck) HTTPScheme() string {
+	if h.HTTP != nil && h.HTTP.HTTPS {
+		return "https"
+	}
+	return "http"
+}
+
+// HTTPHealthCheck are more options of HTTP check on HTTPPort.
+// Method is GET by default, Headers can override Host.
+// With HTTPS, the certificate is verified by CA (PEM encoded) or system CAs, unless SkipVerify.
+// Body and BodyRegexp match the first MaxBodySize bytes of response body, which is 64KiB by default
+type HTTPHealthCheck struct {
+	Method      string
+	Headers     map[string]string
+	HTTPS       bool
+	SkipVerify  bool
+	CA          string
+	Body        string
+	BodyRegexp  string
+	MaxBodySize int64
+}
+
+// CmdHealthCheck runs a command inside the workload, it passes if the command exits with ExitCode.
+// Timeout is in seconds, 0 means the timeout of healthcheck config
+type CmdHealthCheck struct {
+	Command  []string
+	Timeout  int
+	ExitCode int
+}
+
+// GRPCHealthCheck calls grpc.health.v1.Health/Check of the service on Port, it passes if the service is serving.
+// Empty Service means the overall health of the server, SkipVerify skips verifying the certificate with TLS.
+// Timeout is in seconds, 0 means the timeout of healthcheck config
+type GRPCHealthCheck struct {
+	Port       string
+	Service    string
+	TLS        bool
+	SkipVerify bool
+	Timeout    int
+}
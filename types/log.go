@@ -3,6 +3,9 @@ package types
 import (
 	"bufio"
 	"net"
+	"regexp"
+	"strings"
+	"time"
 )
 
 // Log for log
@@ -17,6 +20,36 @@ type Log struct {
 	Extra      map[string]string `json:"extra"`
 }
 
+// LogQuery for subscribing logs of an app
+// Tail and Since pick the recent logs to replay before the live ones, zero values mean no replay.
+// The other fields filter logs, empty ones match anything.
+type LogQuery struct {
+	App   string
+	Tail  int
+	Since time.Time
+
+	EntryPoint string
+	Ident      string
+	ID         string
+	Type       string
+	Contains   string
+	Regexp     *regexp.Regexp
+}
+
+// Match returns whether the log passes the filters
+func (q *LogQuery) Match(log *Log) bool {
+	switch {
+	case q.EntryPoint != "" && q.EntryPoint != log.EntryPoint,
+		q.Ident != "" && q.Ident != log.Ident,
+		q.ID != "" && q.ID != log.ID,
+		q.Type != "" && q.Type != log.Type,
+		q.Contains != "" && !strings.Contains(log.Data, q.Contains),
+		q.Regexp != nil && !q.Regexp.MatchString(log.Data):
+		return false
+	}
+	return true
+}
+
 // LogConsumer for log consumer
 type LogConsumer struct {
 	ID   string
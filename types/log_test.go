@@ -0,0 +1,32 @@
+package types
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogQueryMatch(t *testing.T) {
+	log := &Log{ID: "Rei", Name: "nerv", Type: "stderr", EntryPoint: "eva0", Ident: "boiled", Data: "sync rate 41%"}
+	assert.True(t, (&LogQuery{App: "nerv"}).Match(log))
+	assert.True(t, (&LogQuery{
+		EntryPoint: "eva0",
+		Ident:      "boiled",
+		ID:         "Rei",
+		Type:       "stderr",
+		Contains:   "rate",
+		Regexp:     regexp.MustCompile(`\d+%`),
+	}).Match(log))
+
+	for _, query := range []*LogQuery{
+		{EntryPoint: "eva1"},
+		{Ident: "related"},
+		{ID: "Shinji"},
+		{Type: "stdout"},
+		{Contains: "angel"},
+		{Regexp: regexp.MustCompile(`^\d+`)},
+	} {
+		assert.False(t, query.Match(log))
+	}
+}
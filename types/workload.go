@@ -1,13 +1,33 @@
 package types
 
+import "time"
+
 // WorkloadStatus .
 type WorkloadStatus struct {
-	ID         string
-	Running    bool
-	Healthy    bool
-	Networks   map[string]string
-	Extension  []byte
-	Appname    string
-	Nodename   string
-	Entrypoint string
+	ID         string            `json:"id"`
+	Running    bool              `json:"running"`
+	Healthy    bool              `json:"healthy"`
+	Networks   map[string]string `json:"networks"`
+	Extension  []byte            `json:"extension"`
+	Appname    string            `json:"appname"`
+	Nodename   string            `json:"nodename"`
+	Entrypoint string            `json:"entrypoint"`
+}
+
+// WorkloadState is what agent knows about a workload:
+// the last status computed, when it was checked and reported to store, and whether its logs are attached.
+// Successes and Failures count the consecutive results of health check, which decide the Healthy of status.
+// Zero times mean never.
+type WorkloadState struct {
+	ID          string          `json:"id"`
+	Status      *WorkloadStatus `json:"status"`
+	CheckedAt   time.Time       `json:"checked_at"`
+	StartedAt   time.Time       `json:"started_at"`
+	Successes   int             `json:"consecutive_successes"`
+	Failures    int             `json:"consecutive_failures"`
+	ReportedAt  time.Time       `json:"reported_at"`
+	ReportError string          `json:"report_error,omitempty"`
+	Attached    bool            `json:"attached"`
+	AttachedAt  time.Time       `json:"attached_at"`
+	DetachedAt  time.Time       `json:"detached_at"`
 }
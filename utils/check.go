@@ -1,21 +1,48 @@
 package utils
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
 	"net"
 	"net/http"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/projecteru2/agent/types"
+
+	"github.com/pkg/errors"
 	"github.com/projecteru2/core/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
+// ExecFunc runs the command inside a workload, returns the exit code
+type ExecFunc func(ctx context.Context, cmd []string) (int, error)
+
+const defaultMaxBodySize = 64 * 1024
+
 // CheckHTTP 检查一个workload的所有URL
 // CheckHTTP 事实上一般也就一个
-func CheckHTTP(ctx context.Context, ID string, backends []string, code int, timeout time.Duration) bool {
+// options can be nil, then it's a bare GET
+func CheckHTTP(ctx context.Context, ID string, backends []string, code int, options *types.HTTPHealthCheck, timeout time.Duration) bool {
 	logger := log.WithFunc("CheckHTTP").WithField("ID", ID).WithField("backends", backends).WithField("code", code)
+	if len(backends) == 0 {
+		return true
+	}
+	client, err := newHTTPClient(options)
+	if err != nil {
+		logger.Error(ctx, err, "Invalid http check")
+		return false
+	}
 	for _, backend := range backends {
 		logger.Debug(ctx, "Check health via http")
-		if !checkOneURL(ctx, backend, code, timeout) {
+		if !checkOneURL(ctx, client, backend, code, options, timeout) {
 			logger.Info(ctx, "Check health failed via http")
 			return false
 		}
@@ -39,17 +66,116 @@ func CheckTCP(ctx context.Context, ID string, backends []string, timeout time.Du
 	return true
 }
 
+// CheckCmd runs the command inside a workload, checks its exit code
+func CheckCmd(ctx context.Context, ID string, check *types.CmdHealthCheck, exec ExecFunc, timeout time.Duration) bool {
+	if check == nil || len(check.Command) == 0 {
+		return true
+	}
+	logger := log.WithFunc("CheckCmd").WithField("ID", ID).WithField("command", check.Command)
+	if check.Timeout > 0 {
+		timeout = time.Duration(check.Timeout) * time.Second
+	}
+	logger.Debug(ctx, "Check health via command")
+	var exitCode int
+	var err error
+	WithTimeout(ctx, timeout, func(ctx context.Context) {
+		exitCode, err = exec(ctx, check.Command)
+	})
+	if err != nil {
+		logger.Error(ctx, err, "Error when checking")
+		return false
+	}
+	if exitCode != check.ExitCode {
+		logger.Infof(ctx, "Check health failed via command, expect exit code %d, got %d", check.ExitCode, exitCode)
+		return false
+	}
+	return true
+}
+
+// CheckGRPC checks the backends by grpc health checking protocol
+func CheckGRPC(ctx context.Context, ID string, backends []string, check *types.GRPCHealthCheck, timeout time.Duration) bool {
+	if check == nil {
+		return true
+	}
+	logger := log.WithFunc("CheckGRPC").WithField("ID", ID).WithField("backends", backends).WithField("service", check.Service)
+	if check.Timeout > 0 {
+		timeout = time.Duration(check.Timeout) * time.Second
+	}
+	for _, backend := range backends {
+		logger.Debug(ctx, "Check health via grpc")
+		var err error
+		WithTimeout(ctx, timeout, func(ctx context.Context) {
+			err = checkOneGRPC(ctx, backend, check)
+		})
+		if err != nil {
+			logger.Infof(ctx, "Check health failed via grpc, %s", err)
+			return false
+		}
+	}
+	return true
+}
+
+func checkOneGRPC(ctx context.Context, backend string, check *types.GRPCHealthCheck) error {
+	creds := insecure.NewCredentials()
+	if check.TLS {
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: check.SkipVerify}) //nolint:gosec
+	}
+	conn, err := grpc.DialContext(ctx, backend, grpc.WithTransportCredentials(creds), grpc.WithBlock())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: check.Service})
+	if err != nil {
+		return err
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return errors.Errorf("status %s", resp.Status)
+	}
+	return nil
+}
+
+// newHTTPClient returns the default client for bare checks, or a client for the TLS options
+func newHTTPClient(options *types.HTTPHealthCheck) (*http.Client, error) {
+	if options == nil || !options.HTTPS || (!options.SkipVerify && options.CA == "") {
+		return http.DefaultClient, nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: options.SkipVerify} //nolint:gosec
+	if options.CA != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(options.CA)) {
+			return nil, errors.New("invalid CA")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	// the client is used only once, so don't keep the connections
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig, DisableKeepAlives: true}}, nil
+}
+
 // 偷来的函数
 // 谁要官方的context没有收录他 ¬ ¬
-func get(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+func do(ctx context.Context, client *http.Client, url string, options *types.HTTPHealthCheck) (*http.Response, error) {
 	if client == nil {
 		client = http.DefaultClient
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	method := http.MethodGet
+	if options != nil && options.Method != "" {
+		method = options.Method
+	}
+	req, err := http.NewRequest(method, url, nil)
 	if err != nil {
 		return nil, err
 	}
+	if options != nil {
+		for key, value := range options.Headers {
+			if strings.EqualFold(key, "Host") {
+				req.Host = value
+				continue
+			}
+			req.Header.Set(key, value)
+		}
+	}
 
 	resp, err := client.Do(req.WithContext(ctx))
 	if err != nil {
@@ -63,23 +189,60 @@ func get(ctx context.Context, client *http.Client, url string) (*http.Response,
 }
 
 // 就先定义 [200, 500) 这个区间的 code 都算是成功吧
-func checkOneURL(ctx context.Context, url string, expectedCode int, timeout time.Duration) bool {
+func checkOneURL(ctx context.Context, client *http.Client, url string, expectedCode int, options *types.HTTPHealthCheck, timeout time.Duration) bool {
 	logger := log.WithFunc("checkOneURL").WithField("url", url)
-	var resp *http.Response
-	var err error
+	var ok bool
 	WithTimeout(ctx, timeout, func(ctx context.Context) {
-		resp, err = get(ctx, nil, url) //nolint
+		resp, err := do(ctx, client, url, options)
+		if err != nil {
+			logger.Error(ctx, err, "Error when checking")
+			return
+		}
+		defer resp.Body.Close()
+		if !checkCode(resp.StatusCode, expectedCode) {
+			logger.Warnf(ctx, "Error when checking, expect %d, got %d", expectedCode, resp.StatusCode)
+			return
+		}
+		if err := checkBody(resp.Body, options); err != nil {
+			logger.Warnf(ctx, "Error when checking body, %s", err)
+			return
+		}
+		ok = true
 	})
+	return ok
+}
+
+func checkCode(code, expectedCode int) bool {
+	if expectedCode == 0 {
+		return code < 500 && code >= 200
+	}
+	return code == expectedCode
+}
+
+// checkBody matches the beginning of body
+func checkBody(body io.Reader, options *types.HTTPHealthCheck) error {
+	if options == nil || (options.Body == "" && options.BodyRegexp == "") {
+		return nil
+	}
+	maxBodySize := options.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMaxBodySize
+	}
+	data, err := io.ReadAll(io.LimitReader(body, maxBodySize))
 	if err != nil {
-		logger.Error(ctx, err, "Error when checking")
-		return false
+		return err
 	}
-	defer resp.Body.Close()
-	if expectedCode == 0 {
-		return resp.StatusCode < 500 && resp.StatusCode >= 200
+	if options.Body != "" && !bytes.Contains(data, []byte(options.Body)) {
+		return errors.Errorf("body doesn't contain %s", options.Body)
 	}
-	if resp.StatusCode != expectedCode {
-		logger.Warnf(ctx, "Error when checking, expect %d, got %d", expectedCode, resp.StatusCode)
+	if options.BodyRegexp != "" {
+		re, err := regexp.Compile(options.BodyRegexp)
+		if err != nil {
+			return err
+		}
+		if !re.Match(data) {
+			return errors.Errorf("body doesn't match %s", options.BodyRegexp)
+		}
 	}
-	return resp.StatusCode == expectedCode
+	return nil
 }
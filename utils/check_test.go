@@ -2,25 +2,129 @@ package utils
 
 import (
 	"context"
+	"encoding/pem"
+	"errors"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/projecteru2/agent/types"
+
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func TestCheck(t *testing.T) {
 	go http.ListenAndServe(":12306", http.NotFoundHandler())
 	time.Sleep(time.Second)
 	ctx, cancel := context.WithCancel(context.Background())
-	assert.Equal(t, CheckHTTP(ctx, "", []string{"http://127.0.0.1:12306"}, 404, time.Second), true)
-	assert.Equal(t, CheckHTTP(ctx, "", []string{"http://127.0.0.1:12306"}, 0, time.Second), true)
-	assert.Equal(t, CheckHTTP(ctx, "", []string{"http://127.0.0.1:12306"}, 200, time.Second), false)
-	assert.Equal(t, CheckHTTP(ctx, "", []string{"http://127.0.0.1:12307"}, 200, time.Second), false)
+	assert.Equal(t, CheckHTTP(ctx, "", []string{"http://127.0.0.1:12306"}, 404, nil, time.Second), true)
+	assert.Equal(t, CheckHTTP(ctx, "", []string{"http://127.0.0.1:12306"}, 0, nil, time.Second), true)
+	assert.Equal(t, CheckHTTP(ctx, "", []string{"http://127.0.0.1:12306"}, 200, nil, time.Second), false)
+	assert.Equal(t, CheckHTTP(ctx, "", []string{"http://127.0.0.1:12307"}, 200, nil, time.Second), false)
 
 	cancel()
-	assert.Equal(t, CheckHTTP(ctx, "", []string{"http://127.0.0.1:12306"}, 404, time.Second), false)
+	assert.Equal(t, CheckHTTP(ctx, "", []string{"http://127.0.0.1:12306"}, 404, nil, time.Second), false)
 
 	assert.Equal(t, CheckTCP(ctx, "", []string{"127.0.0.1:12306"}, time.Second), true)
 	assert.Equal(t, CheckTCP(ctx, "", []string{"127.0.0.1:12307"}, time.Second), false)
 }
+
+func TestCheckCmd(t *testing.T) {
+	ctx := context.Background()
+	exec := func(ctx context.Context, cmd []string) (int, error) {
+		switch cmd[0] {
+		case "sleep":
+			<-ctx.Done()
+			return 0, ctx.Err()
+		case "false":
+			return 1, nil
+		case "broken":
+			return 0, errors.New("no such container")
+		}
+		return 0, nil
+	}
+
+	assert.True(t, CheckCmd(ctx, "", nil, exec, time.Second))
+	assert.True(t, CheckCmd(ctx, "", &types.CmdHealthCheck{}, exec, time.Second))
+	assert.True(t, CheckCmd(ctx, "", &types.CmdHealthCheck{Command: []string{"true"}}, exec, time.Second))
+	assert.False(t, CheckCmd(ctx, "", &types.CmdHealthCheck{Command: []string{"false"}}, exec, time.Second))
+	assert.True(t, CheckCmd(ctx, "", &types.CmdHealthCheck{Command: []string{"false"}, ExitCode: 1}, exec, time.Second))
+	assert.False(t, CheckCmd(ctx, "", &types.CmdHealthCheck{Command: []string{"broken"}}, exec, time.Second))
+
+	// the timeout of check overrides the default one
+	start := time.Now()
+	assert.False(t, CheckCmd(ctx, "", &types.CmdHealthCheck{Command: []string{"sleep"}, Timeout: 1}, exec, time.Hour))
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.False(t, CheckCmd(ctx, "", &types.CmdHealthCheck{Command: []string{"sleep"}}, exec, 10*time.Millisecond))
+}
+
+func TestCheckGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("nerv", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("seele", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	ctx := context.Background()
+	backends := []string{lis.Addr().String()}
+	assert.True(t, CheckGRPC(ctx, "", backends, nil, time.Second))
+	assert.True(t, CheckGRPC(ctx, "", backends, &types.GRPCHealthCheck{}, time.Second))
+	assert.True(t, CheckGRPC(ctx, "", backends, &types.GRPCHealthCheck{Service: "nerv"}, time.Second))
+	assert.False(t, CheckGRPC(ctx, "", backends, &types.GRPCHealthCheck{Service: "seele"}, time.Second))
+	assert.False(t, CheckGRPC(ctx, "", backends, &types.GRPCHealthCheck{Service: "gehirn"}, time.Second))
+	// the server doesn't speak TLS
+	assert.False(t, CheckGRPC(ctx, "", backends, &types.GRPCHealthCheck{TLS: true, SkipVerify: true}, 100*time.Millisecond))
+
+	healthServer.Shutdown()
+	assert.False(t, CheckGRPC(ctx, "", backends, &types.GRPCHealthCheck{Service: "nerv"}, time.Second))
+	server.Stop()
+	assert.False(t, CheckGRPC(ctx, "", backends, &types.GRPCHealthCheck{}, 100*time.Millisecond))
+}
+
+func TestCheckHTTPOptions(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodHead && req.Host == "nerv.local" && req.Header.Get("X-Pilot") == "rei" {
+			_, _ = w.Write([]byte(`{"pilot":"rei","status":"ok"}` + strings.Repeat(" ", 100) + "tail"))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	ctx := context.Background()
+	backends := []string{server.URL}
+	headers := map[string]string{"host": "nerv.local", "X-Pilot": "rei"}
+
+	assert.False(t, CheckHTTP(ctx, "", backends, 200, nil, time.Second))
+	assert.True(t, CheckHTTP(ctx, "", backends, 200, &types.HTTPHealthCheck{Headers: headers}, time.Second))
+	assert.False(t, CheckHTTP(ctx, "", backends, 200, &types.HTTPHealthCheck{Method: http.MethodHead, Headers: headers}, time.Second))
+	assert.True(t, CheckHTTP(ctx, "", backends, 200, &types.HTTPHealthCheck{Headers: headers, Body: `"status":"ok"`}, time.Second))
+	assert.False(t, CheckHTTP(ctx, "", backends, 200, &types.HTTPHealthCheck{Headers: headers, Body: `"status":"down"`}, time.Second))
+	assert.True(t, CheckHTTP(ctx, "", backends, 200, &types.HTTPHealthCheck{Headers: headers, BodyRegexp: `"pilot":"(rei|asuka)"`}, time.Second))
+	assert.False(t, CheckHTTP(ctx, "", backends, 200, &types.HTTPHealthCheck{Headers: headers, BodyRegexp: `"pilot":"shinji"`}, time.Second))
+	assert.False(t, CheckHTTP(ctx, "", backends, 200, &types.HTTPHealthCheck{Headers: headers, BodyRegexp: `(`}, time.Second))
+	// only the beginning of body is read
+	assert.True(t, CheckHTTP(ctx, "", backends, 200, &types.HTTPHealthCheck{Headers: headers, Body: "tail"}, time.Second))
+	assert.False(t, CheckHTTP(ctx, "", backends, 200, &types.HTTPHealthCheck{Headers: headers, Body: "tail", MaxBodySize: 64}, time.Second))
+
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+	backends = []string{tlsServer.URL}
+	ca := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw}))
+	assert.False(t, CheckHTTP(ctx, "", backends, 200, &types.HTTPHealthCheck{HTTPS: true, Headers: headers}, time.Second))
+	assert.True(t, CheckHTTP(ctx, "", backends, 200, &types.HTTPHealthCheck{HTTPS: true, SkipVerify: true, Headers: headers}, time.Second))
+	assert.True(t, CheckHTTP(ctx, "", backends, 200, &types.HTTPHealthCheck{HTTPS: true, CA: ca, Headers: headers}, time.Second))
+	assert.False(t, CheckHTTP(ctx, "", backends, 200, &types.HTTPHealthCheck{HTTPS: true, CA: "nerv", Headers: headers}, time.Second))
+	assert.Equal(t, "https", (&types.HealthCheck{HTTP: &types.HTTPHealthCheck{HTTPS: true}}).HTTPScheme())
+	assert.Equal(t, "http", (&types.HealthCheck{}).HTTPScheme())
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"net/url"
@@ -16,6 +17,7 @@ import (
 	"github.com/projecteru2/agent/common"
 	"github.com/projecteru2/agent/types"
 	"github.com/projecteru2/agent/version"
+	"github.com/projecteru2/core/cluster"
 	coreutils "github.com/projecteru2/core/utils"
 	yavirtclient "github.com/projecteru2/libyavirt/client"
 	yavirttypes "github.com/projecteru2/libyavirt/types"
@@ -107,6 +109,30 @@ func IsDockerized() bool {
 	return dockerized
 }
 
+// DecodeMetaInLabel decodes the meta in ERU_META label, the same as core but with agent's health check
+func DecodeMetaInLabel(ctx context.Context, labels map[string]string) *types.LabelMeta {
+	meta := &types.LabelMeta{}
+	if metastr, ok := labels[cluster.LabelMeta]; ok {
+		if err := json.Unmarshal([]byte(metastr), meta); err != nil {
+			log.WithFunc("utils.DecodeMetaInLabel").Error(ctx, err, "Decode failed")
+		}
+	}
+	return meta
+}
+
+// GetHealthCheckTimeout returns the timeout of health check, the config can be overridden by the workload's label
+func GetHealthCheckTimeout(config *types.Config, labels map[string]string) time.Duration {
+	timeout := config.HealthCheck.Timeout
+	if value, ok := labels[common.ERUHealthCheckTimeout]; ok {
+		if t, err := strconv.Atoi(value); err == nil && t > 0 {
+			timeout = t
+		} else {
+			log.WithFunc("utils.GetHealthCheckTimeout").Warnf(nil, "invalid health check timeout %s", value) //nolint
+		}
+	}
+	return time.Duration(timeout) * time.Second
+}
+
 // WithTimeout runs a function with given timeout
 func WithTimeout(ctx context.Context, timeout time.Duration, f func(ctx2 context.Context)) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
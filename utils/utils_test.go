@@ -12,6 +12,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/projecteru2/agent/types"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -191,3 +193,28 @@ func TestWithTimeout(t *testing.T) {
 
 	assert.NotEqual(t, i, 2)
 }
+
+func TestDecodeMetaInLabel(t *testing.T) {
+	ctx := context.Background()
+	meta := DecodeMetaInLabel(ctx, map[string]string{})
+	assert.Nil(t, meta.HealthCheck)
+
+	meta = DecodeMetaInLabel(ctx, map[string]string{"ERU_META": `{"Publish":["80"],"HealthCheck":{"TCPPorts":["80"],"Cmd":{"Command":["pgrep","worker"],"Timeout":3}}}`})
+	assert.Equal(t, []string{"80"}, meta.Publish)
+	assert.Equal(t, []string{"80"}, meta.HealthCheck.TCPPorts)
+	assert.Equal(t, []string{"pgrep", "worker"}, meta.HealthCheck.Cmd.Command)
+	assert.Equal(t, 3, meta.HealthCheck.Cmd.Timeout)
+	assert.Equal(t, 0, meta.HealthCheck.Cmd.ExitCode)
+
+	// invalid meta is ignored
+	meta = DecodeMetaInLabel(ctx, map[string]string{"ERU_META": "{"})
+	assert.Nil(t, meta.HealthCheck)
+}
+
+func TestGetHealthCheckTimeout(t *testing.T) {
+	config := &types.Config{HealthCheck: types.HealthCheckConfig{Timeout: 10}}
+	assert.Equal(t, 10*time.Second, GetHealthCheckTimeout(config, nil))
+	assert.Equal(t, 3*time.Second, GetHealthCheckTimeout(config, map[string]string{"eru.healthcheck.timeout": "3"}))
+	assert.Equal(t, 10*time.Second, GetHealthCheckTimeout(config, map[string]string{"eru.healthcheck.timeout": "0"}))
+	assert.Equal(t, 10*time.Second, GetHealthCheckTimeout(config, map[string]string{"eru.healthcheck.timeout": "soon"}))
+}